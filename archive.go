@@ -0,0 +1,141 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const STDIN_FILE_NAME string = "-"
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zipMagic  = []byte("PK\x03\x04")
+)
+
+// Read a game file, unpacking it if it's compressed or archived. Besides the
+// data, the name of the file inside the archive is returned
+func (game *gameEngine) readGameFile(gameFile string) ([]byte, string, error) {
+	var data []byte
+	var err error
+	if gameFile == STDIN_FILE_NAME {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(gameFile)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return game.unpackGameData(data, gameFile)
+}
+
+// Archives are recognized by their magic bytes, not by their names
+func (game *gameEngine) unpackGameData(data []byte, name string) ([]byte, string, error) {
+	switch {
+	case bytes.HasPrefix(data, gzipMagic):
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, "", fmt.Errorf("gzip data in %s: %v", name, err)
+		}
+		unpacked, err := ioutil.ReadAll(reader)
+		if err != nil {
+			return nil, "", fmt.Errorf("gzip data in %s: %v", name, err)
+		}
+		memberName := reader.Name
+		if memberName == "" {
+			memberName = strings.TrimSuffix(name, filepath.Ext(name))
+		}
+		return game.unpackGameData(unpacked, memberName)
+	case bytes.HasPrefix(data, zipMagic):
+		return game.readZipMember(data, name)
+	}
+	return data, name, nil
+}
+
+// Files that are likely to be game data, judging by their names
+func isGameFileName(name string) bool {
+	extension := strings.ToLower(filepath.Ext(name))
+	return extension == ".dat" || extension == ".gz"
+}
+
+// Pick the game file in a zip archive. If there are several, the player
+// chooses one
+func (game *gameEngine) readZipMember(data []byte, name string) ([]byte, string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, "", fmt.Errorf("zip archive %s: %v", name, err)
+	}
+
+	var candidates []*zip.File
+	var otherFiles []*zip.File
+	for _, member := range archive.File {
+		if member.FileInfo().IsDir() {
+			continue
+		}
+		if isGameFileName(member.Name) {
+			candidates = append(candidates, member)
+		} else {
+			otherFiles = append(otherFiles, member)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = otherFiles
+	}
+	if len(candidates) == 0 {
+		return nil, "", fmt.Errorf("zip archive %s is empty", name)
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Name < candidates[j].Name })
+
+	member := candidates[0]
+	if len(candidates) > 1 && !game.scanning {
+		member = game.chooseZipMember(candidates)
+	}
+
+	reader, err := member.Open()
+	if err != nil {
+		return nil, "", fmt.Errorf("archive member %s: %v", member.Name, err)
+	}
+	defer reader.Close()
+	unpacked, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, "", fmt.Errorf("archive member %s: %v", member.Name, err)
+	}
+	return game.unpackGameData(unpacked, member.Name)
+}
+
+func (game *gameEngine) chooseZipMember(candidates []*zip.File) *zip.File {
+	fmt.Fprintln(game.output, localize("The archive holds several games:"))
+	for number, member := range candidates {
+		fmt.Fprintf(game.output, "%3d. %s\n", number+1, member.Name)
+	}
+	for {
+		fmt.Fprintf(game.output, localize("Which one do you want to play? (1-%d)\n"), len(candidates))
+		answer, inputAvailable := game.readInputLine()
+		if !inputAvailable {
+			return candidates[0]
+		}
+		number, err := strconv.Atoi(strings.TrimSpace(answer))
+		if err == nil && number >= 1 && number <= len(candidates) {
+			return candidates[number-1]
+		}
+	}
+}
+
+// When the game data is read from standard input, commands have to come from
+// the terminal instead
+func (game *gameEngine) useTerminalForInput() {
+	terminal, err := os.Open("/dev/tty")
+	if err != nil {
+		return
+	}
+	inputSource = terminal
+	game.inputReader = bufio.NewReader(terminal)
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+var (
+	autosaveInterval int
+	lastAutosaveTurn int
+	recoveryMutex    sync.Mutex
+	recoveryPoint    *gameSnapshot
+)
+
+// The recovery slot is tied to the contents of the game file, so that a
+// different game with the same adventure number doesn't pick it up
+func (game *gameEngine) recoverySaveSlot() string {
+	return fmt.Sprintf("recovery-%08x", game.gameFileChecksum)
+}
+
+// Remember the last state between two turns. This is what gets written to the
+// recovery slot, since the game state may be half updated when a signal arrives
+func setRecoveryPoint(snapshot gameSnapshot) {
+	recoveryMutex.Lock()
+	defer recoveryMutex.Unlock()
+	recoveryPoint = &snapshot
+}
+
+func (game *gameEngine) writeRecoverySave() error {
+	recoveryMutex.Lock()
+	defer recoveryMutex.Unlock()
+	if recoveryPoint == nil {
+		return nil
+	}
+	saveFileName, err := game.saveFilePath(game.recoverySaveSlot())
+	if err != nil {
+		return err
+	}
+	return game.writeSaveFile(saveFileName, *recoveryPoint)
+}
+
+func (game *gameEngine) removeRecoverySave() {
+	saveFileName, err := game.saveFilePath(game.recoverySaveSlot())
+	if err != nil {
+		return
+	}
+	if err := os.Remove(saveFileName); err != nil && !errors.Is(err, os.ErrNotExist) {
+		fmt.Fprintf(game.output, localize("Couldn't remove autosaved game: %v\n"), err)
+	}
+}
+
+// Save to the recovery slot every autosaveInterval turns
+func (game *gameEngine) autosaveTurn() {
+	if autosaveInterval < 1 || game.turnCounter == lastAutosaveTurn || game.turnCounter%autosaveInterval != 0 {
+		return
+	}
+	lastAutosaveTurn = game.turnCounter
+	if err := game.writeRecoverySave(); err != nil {
+		fmt.Fprintf(game.output, localize("Couldn't autosave game: %v\n"), err)
+	}
+}
+
+// Write the recovery slot and put the terminal back in order when the
+// interpreter is interrupted or killed
+func (game *gameEngine) handleSignals() {
+	if autosaveInterval < 1 && !tuiActive {
+		return
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	go func() {
+		<-signals
+		game.closeTUI()
+		game.closeRemGlk()
+		fmt.Fprintln(game.output)
+		if autosaveInterval > 0 {
+			if err := game.writeRecoverySave(); err != nil {
+				fmt.Fprintf(game.output, localize("Couldn't autosave game: %v\n"), err)
+			} else {
+				fmt.Fprintln(game.output, localize("Game autosaved"))
+			}
+		}
+		os.Exit(1)
+	}()
+}
+
+// Leave the game, keeping the recovery slot up to date
+func (game *gameEngine) exitGame() {
+	if game.sessionRunning {
+		panic(gameOverSignal{})
+	}
+	game.closeTUI()
+	game.closeRemGlk()
+	if autosaveInterval > 0 {
+		if err := game.writeRecoverySave(); err != nil {
+			fmt.Fprintf(game.output, localize("Couldn't autosave game: %v\n"), err)
+		}
+	}
+	os.Exit(0)
+}
+
+// The game has come to an end, so there is nothing left to recover
+func (game *gameEngine) endGame() {
+	if game.sessionRunning {
+		panic(gameOverSignal{})
+	}
+	game.closeTUI()
+	game.closeRemGlk()
+	game.removeRecoverySave()
+	os.Exit(0)
+}
+
+// Offer to continue a game that was autosaved when the interpreter last quit
+func (game *gameEngine) offerRecovery() bool {
+	saveFileName, err := game.saveFilePath(game.recoverySaveSlot())
+	if err != nil {
+		return false
+	}
+	snapshot, err := game.readSaveFile(saveFileName)
+	if err != nil {
+		return false
+	}
+	question := fmt.Sprintf(localize("An autosaved game was found (%s, %d turns). Resume it? (Y/N)"),
+		game.roomName(snapshot.currentRoom), snapshot.turnCounter)
+	if !game.confirm(question) {
+		game.removeRecoverySave()
+		return false
+	}
+	game.restoreSnapshot(snapshot)
+	lastAutosaveTurn = game.turnCounter
+	return true
+}
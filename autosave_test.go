@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// A game autosaved to the recovery slot is offered when the same game is
+// played again, and the slot is gone once the offer is turned down
+func TestRecovery(t *testing.T) {
+	saveRoot := t.TempDir()
+	defer func() { recoveryPoint, lastAutosaveTurn = nil, 0 }()
+
+	game := loadFixture(t, "adventure.dat")
+	game.saveRoot = saveRoot
+	game.initializeGameState()
+	game.playCommands("GET LAMP\nS\n")
+	saved := game.takeSnapshot()
+	setRecoveryPoint(saved)
+	game.playCommands("N\n")
+	if err := game.writeRecoverySave(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another game doesn't pick it up
+	other := loadFixture(t, "howarth1.dat")
+	other.saveRoot = saveRoot
+	other.initializeGameState()
+	if other.offerRecovery() {
+		t.Error("the recovery slot was offered for another game")
+	}
+
+	resume := func(answer string) (*gameEngine, bool) {
+		game := loadFixture(t, "adventure.dat")
+		game.saveRoot = saveRoot
+		game.inputReader = bufio.NewReader(strings.NewReader(answer))
+		game.initializeGameState()
+		return game, game.offerRecovery()
+	}
+	resumed, offered := resume("Y\n")
+	if !offered {
+		t.Fatal("the recovery slot wasn't offered")
+	}
+	if resumed.currentRoom != saved.currentRoom || resumed.turnCounter != saved.turnCounter ||
+		resumed.objectLocation[LIGHT_SOURCE_ID] != ROOM_INVENTORY {
+		t.Errorf("resumed in room %d after %d turns, wanted room %d after %d with the lamp",
+			resumed.currentRoom, resumed.turnCounter, saved.currentRoom, saved.turnCounter)
+	}
+
+	if _, resumed := resume("N\n"); resumed {
+		t.Error("the game was resumed when the offer was turned down")
+	}
+	if _, offered := resume("Y\n"); offered {
+		t.Error("the recovery slot was offered after being turned down")
+	}
+	if files, _ := os.ReadDir(saveRoot); len(files) != 0 {
+		t.Errorf("got %s left, wanted the recovery slot removed", filepath.Join(saveRoot, files[0].Name()))
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+)
+
+// The Brian Howarth games (Mysterious Adventures and others) use the Scott
+// Adams format with a few extensions: line breaks in texts are written as
+// backslashes, there are extra commands after PIC, and a game can be split
+// into several parts, each in a file of its own.
+
+const (
+	DIALECT_AUTO     string = "auto"
+	DIALECT_HOWARTH  string = "howarth"
+	DIALECT_SCOTT    string = "scott"
+	HOWARTH_COMMANDS int    = 40
+	SCOTT_COMMANDS   int    = 38
+)
+
+var selectedDialect string = DIALECT_AUTO
+
+// Number of commands available in the dialect of the loaded game
+func (game *gameEngine) dialectCommands() int {
+	if game.gameDialect == DIALECT_HOWARTH {
+		return HOWARTH_COMMANDS
+	}
+	return SCOTT_COMMANDS
+}
+
+// Use the dialect given on the command line, or find out which one the loaded
+// game uses from the commands in its actions
+func (game *gameEngine) selectDialect() error {
+	switch selectedDialect {
+	case DIALECT_SCOTT, DIALECT_HOWARTH:
+		game.gameDialect = selectedDialect
+	case DIALECT_AUTO:
+		game.gameDialect = DIALECT_SCOTT
+		if game.usesHowarthCommands() {
+			game.gameDialect = DIALECT_HOWARTH
+		}
+	default:
+		return fmt.Errorf("unknown dialect \"%s\"", selectedDialect)
+	}
+
+	if game.gameDialect == DIALECT_HOWARTH {
+		replaceInStringSlice(game.message, `\`, "\n")
+		replaceInStringSlice(game.roomDescription, `\`, "\n")
+	}
+	return nil
+}
+
+func (game *gameEngine) usesHowarthCommands() bool {
+	for actionId := range game.actionData {
+		for command := 0; command < COMMANDS_IN_ACTION; command++ {
+			commandCode := game.decodeCommandFromData(command, actionId) - MESSAGE_1_END - 1
+			if commandCode >= SCOTT_COMMANDS && commandCode < HOWARTH_COMMANDS {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// The file of the next part has the same name as the current one, with the
+// last number in the name counted up
+func nextPartFile(currentFile string) (string, bool) {
+	matches := regexp.MustCompile(`^(.*?)(\d+)(\D*)$`).FindStringSubmatch(filepath.Base(currentFile))
+	if matches == nil {
+		return "", false
+	}
+	part, _ := strconv.Atoi(matches[2])
+	nextFile := filepath.Join(filepath.Dir(currentFile),
+		fmt.Sprintf("%s%0*d%s", matches[1], len(matches[2]), part+1, matches[3]))
+	if _, err := os.Stat(nextFile); err != nil {
+		return "", false
+	}
+	return nextFile, true
+}
+
+// Continue the adventure in its next part. The turn count carries over, but
+// everything else starts over as the new part describes it. Games saved to
+// memory are of the last part, so they are forgotten
+func (game *gameEngine) loadNextPart() {
+	game.nextPartPending = false
+	nextFile, found := nextPartFile(game.gameFile)
+	if !found {
+		fmt.Fprintln(game.output, localize("The next part of this adventure couldn't be found"))
+		game.endGame()
+	}
+
+	fmt.Fprintln(game.output, localize("Loading the next part of the adventure..."))
+	if err := game.loadGameDataFile(nextFile); err != nil {
+		fmt.Fprintf(game.output, localize("Couldn't load \"%s\": %v\n"), nextFile, err)
+		game.endGame()
+	}
+	game.gameFile = nextFile
+
+	turns := game.turnCounter
+	game.initializeGameState()
+	game.turnCounter = turns
+	game.undoHistory = nil
+	game.ramSaveSlot = make(map[string]gameSnapshot)
+	game.startGame()
+}
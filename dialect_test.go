@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNextPartFile(t *testing.T) {
+	directory := filepath.Join(t.TempDir(), "games", "v2")
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"part1.dat", "part2.dat", "adv09.dat", "adv10.dat", "story.dat"} {
+		if err := os.WriteFile(filepath.Join(directory, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		current, next string
+	}{
+		{"part1.dat", "part2.dat"},
+		{"adv09.dat", "adv10.dat"},
+		// There's no third part, and the number in the directory name isn't
+		// the file's
+		{"part2.dat", ""},
+		{"story.dat", ""},
+	}
+	for _, test := range tests {
+		next, found := nextPartFile(filepath.Join(directory, test.current))
+		if test.next == "" {
+			if found {
+				t.Errorf("%s: got next part %s, wanted none", test.current, next)
+			}
+		} else if next != filepath.Join(directory, test.next) {
+			t.Errorf("%s: got next part %q, wanted %s", test.current, next, test.next)
+		}
+	}
+}
+
+// testdata/howarth1.dat has an action for each of the extra commands: PRESS
+// shows a message on each side of WAIT, SHOW draws picture 3 and shows a
+// message, and LEAVE goes on to howarth2.dat with NEXT, before a message that
+// isn't shown
+func TestHowarthCommands(t *testing.T) {
+	game := loadFixture(t, "howarth1.dat")
+	game.gameFile = filepath.Join("testdata", "howarth1.dat")
+	if game.gameDialect != DIALECT_HOWARTH {
+		t.Fatalf("got dialect %s, wanted %s", game.gameDialect, DIALECT_HOWARTH)
+	}
+	game.initializeGameState()
+
+	tests := []struct {
+		name     string
+		commands string
+		wanted   []string
+		unwanted []string
+	}{
+		{"WAIT", "PRESS\n\nLOOK\n", []string{"Before waiting.\nPress Enter to continue\nAfter waiting.\n"}, nil},
+		{"PIC", "SHOW\n", []string{"A picture.\n"}, nil},
+		{"NEXT", "SAVE RAM\nLEAVE\n", []string{"Loading the next part", "hall of the second part"}, []string{"Still in part one."}},
+		{"memory slots of the last part", "RESTORE RAM\n", []string{"Nothing has been saved to memory"}, []string{"restored"}},
+	}
+	for _, test := range tests {
+		var text bytes.Buffer
+		game.output = terminalDisplay{&text}
+		game.playCommands(test.commands)
+		for _, wanted := range test.wanted {
+			if !strings.Contains(text.String(), wanted) {
+				t.Errorf("%s: %q doesn't have %q", test.name, text.String(), wanted)
+			}
+		}
+		for _, unwanted := range test.unwanted {
+			if strings.Contains(text.String(), unwanted) {
+				t.Errorf("%s: %q has %q", test.name, text.String(), unwanted)
+			}
+		}
+	}
+	if game.gameFile != filepath.Join("testdata", "howarth2.dat") {
+		t.Errorf("got game file %s after NEXT, wanted testdata/howarth2.dat", game.gameFile)
+	}
+	if len(game.objectLocation) != 11 {
+		t.Errorf("got %d objects after NEXT, wanted the 11 of the second part", len(game.objectLocation))
+	}
+}
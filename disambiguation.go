@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// When several objects in reach can be called by the same noun, like a rusty
+// key and a brass key, the player can say which one with the words of its
+// description, as in GET BRASS KEY. Otherwise the interpreter asks.
+
+// Words between the verb and a noun are taken to describe which object is
+// meant
+func (game *gameEngine) separateAdjectives() {
+	game.inputAdjectives = nil
+	if len(game.extractedInputWords) < 3 || game.isKnownNoun(game.extractedInputWords[1]) {
+		return
+	}
+	for i := 2; i < len(game.extractedInputWords); i++ {
+		if game.isKnownNoun(game.extractedInputWords[i]) {
+			game.inputAdjectives = append([]string(nil), game.extractedInputWords[1:i]...)
+			game.extractedInputWords = append([]string{game.extractedInputWords[0]}, game.extractedInputWords[i:]...)
+			return
+		}
+	}
+}
+
+func (game *gameEngine) isKnownNoun(word string) bool {
+	for _, words := range game.listOfVerbsAndNouns {
+		noun := strings.TrimPrefix(words[1], "*")
+		if noun != "" && noun != "." && game.sameWord(noun, word) {
+			return true
+		}
+	}
+	return game.isObjectNoun(word) || game.isPronoun(word)
+}
+
+// Of the objects that fit the noun, keep those that fit the describing words
+// as well
+func (game *gameEngine) objectsMatchingWords(objects []int, words []string) []int {
+	var matching []int
+	for _, object := range objects {
+		descriptionWords := strings.FieldsFunc(foldCase(game.stripNounFromObjectDescription(object)), func(character rune) bool {
+			return !unicode.IsLetter(character) && !unicode.IsDigit(character)
+		})
+		matchesAll := true
+		for _, word := range words {
+			found := false
+			for _, descriptionWord := range descriptionWords {
+				if strings.HasPrefix(descriptionWord, foldCase(word)) {
+					found = true
+					break
+				}
+			}
+			matchesAll = matchesAll && found
+		}
+		if matchesAll {
+			matching = append(matching, object)
+		}
+	}
+	return matching
+}
+
+// Let the player pick one of the objects. Returns false if the answer didn't
+// single one out
+func (game *gameEngine) chooseObject(candidates []int) (int, bool) {
+	var descriptions []string
+	for number, object := range candidates {
+		descriptions = append(descriptions, fmt.Sprintf("%d. %s", number+1, unbreakable(game.stripNounFromObjectDescription(object))))
+	}
+	fmt.Fprintf(game.output, localize("Which one? %s\n"), strings.Join(descriptions, " "))
+	answer, _ := game.readInputLine()
+	fmt.Fprintln(game.output)
+
+	if number, err := strconv.Atoi(strings.TrimSpace(answer)); err == nil {
+		if number >= 1 && number <= len(candidates) {
+			return candidates[number-1], true
+		}
+		return 0, false
+	}
+	words := tokenizeInput(answer)
+	if len(words) == 0 {
+		return 0, false
+	}
+	chosen := game.objectsMatchingWords(candidates, words)
+	if len(chosen) != 1 {
+		return 0, false
+	}
+	return chosen[0], true
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// The forest clearing of testdata/adventure.dat has a rusty key and a brass
+// key. Taking a key asks which one, unless the command says
+func TestWhichOne(t *testing.T) {
+	game := loadFixture(t, "adventure.dat")
+	keys := make(map[string]int)
+	for object, description := range game.objectDescription {
+		if strings.HasSuffix(description, "Key/KEY/") {
+			keys[strings.Fields(description)[0]] = object
+		}
+	}
+	if len(keys) != 2 {
+		t.Fatalf("found keys %v, wanted a rusty and a brass one", keys)
+	}
+
+	tests := []struct {
+		name     string
+		commands string
+		asked    bool
+		taken    string
+	}{
+		{"answered with a number", "GET KEY\n2\n", true, "brass"},
+		{"answered with a word", "GET KEY\nRUSTY\n", true, "rusty"},
+		{"answered with both words", "GET KEY\nRUSTY KEY\n", true, "rusty"},
+		{"said in the command", "GET BRASS KEY\n", false, "brass"},
+		{"number out of range", "GET KEY\n3\n", true, ""},
+		{"answer that fits both", "GET KEY\nKEY\n", true, ""},
+		{"no answer", "GET KEY\n\n", true, ""},
+	}
+	for _, test := range tests {
+		var text bytes.Buffer
+		game.output = terminalDisplay{&text}
+		game.initializeGameState()
+		game.playCommands(test.commands)
+		if asked := strings.Contains(text.String(), "Which one? 1. rusty\u00a0Key 2. brass\u00a0Key"); asked != test.asked {
+			t.Errorf("%s: asked which one is %v, wanted %v: %q", test.name, asked, test.asked, text.String())
+		}
+		for adjective, object := range keys {
+			if taken := game.objectLocation[object] == ROOM_INVENTORY; taken != (adjective == test.taken) {
+				t.Errorf("%s: %s key taken is %v", test.name, adjective, taken)
+			}
+		}
+		if test.taken == "" && !strings.Contains(text.String(), "What?") {
+			t.Errorf("%s: %q doesn't have \"What?\"", test.name, text.String())
+		}
+	}
+}
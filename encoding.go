@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Translated games are written in whatever encoding the translator's computer
+// used. The text of a game file is turned into UTF-8 when it's loaded, and
+// words are compared without regard to case in any language.
+
+const (
+	ENCODING_AUTO   string = "auto"
+	ENCODING_CP437  string = "cp437"
+	ENCODING_LATIN1 string = "latin1"
+	ENCODING_UTF8   string = "utf-8"
+)
+
+var gameFileEncoding string = ENCODING_AUTO
+
+var utf8ByteOrderMark = []byte{0xef, 0xbb, 0xbf}
+
+// Characters 128-255 of the IBM PC character set
+var cp437Characters = []rune(
+	"ÇüéâäàåçêëèïîìÄÅÉæÆôöòûùÿÖÜ¢£¥₧ƒ" +
+		"áíóúñÑªº¿⌐¬½¼¡«»░▒▓│┤╡╢╖╕╣║╗╝╜╛┐" +
+		"└┴┬├─┼╞╟╚╔╩╦╠═╬╧╨╤╥╙╘╒╓╫╪┘┌█▄▌▐▀" +
+		"αßΓπΣσµτΦΘΩδ∞φε∩≡±≥≤⌠⌡÷≈°∙·√ⁿ²■\u00a0")
+
+// Turn the text of a game file into UTF-8. Without an encoding given, text
+// that isn't valid UTF-8 is taken to be Latin-1
+func decodeGameText(data []byte) (string, error) {
+	encoding := strings.ToLower(gameFileEncoding)
+	if encoding == ENCODING_AUTO {
+		encoding = ENCODING_LATIN1
+		if utf8.Valid(data) {
+			encoding = ENCODING_UTF8
+		}
+	}
+
+	switch encoding {
+	case ENCODING_UTF8, "utf8":
+		return string(bytes.TrimPrefix(data, utf8ByteOrderMark)), nil
+	case ENCODING_LATIN1, "iso-8859-1":
+		characters := make([]rune, len(data))
+		for i, character := range data {
+			characters[i] = rune(character)
+		}
+		return string(characters), nil
+	case ENCODING_CP437:
+		characters := make([]rune, len(data))
+		for i, character := range data {
+			characters[i] = rune(character)
+			if character >= 128 {
+				characters[i] = cp437Characters[character-128]
+			}
+		}
+		return string(characters), nil
+	}
+	return "", fmt.Errorf("unknown encoding \"%s\"", gameFileEncoding)
+}
+
+// A form of a word that is the same for upper and lower case, also for
+// letters like ß that have no single upper case letter
+func foldCase(word string) string {
+	return strings.ToUpper(strings.ToLower(word))
+}
+
+// Words are the same if the parts of them the game looks at are, whatever
+// their case
+func (game *gameEngine) sameWord(first, second string) bool {
+	return foldCase(extractFirstCharacters(first, game.wordLength)) == foldCase(extractFirstCharacters(second, game.wordLength))
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Each game is played by an engine of its own, which keeps everything about
+// the game: its data, its state and where it's played. The games on a server
+// are played side by side, each in the goroutine of its player, and only the
+// players of a shared world take turns.
+
+// Signals that the game has come to an end, while it's played as a session
+type gameOverSignal struct{}
+
+// Everything the engine keeps about one game
+type gameEngine struct {
+	// The game data
+	actionData             [][]int
+	actionDescription      []string
+	adventureNumber        int
+	adventureVersion       int
+	gameBytes              int
+	gameDialect            string
+	gameFile               string
+	gameFileChecksum       uint32
+	listOfVerbsAndNouns    [][]string
+	maxObjectsCarried      int
+	message                []string
+	numberOfActions        int
+	numberOfMessages       int
+	numberOfObjects        int
+	numberOfRooms          int
+	numberOfTreasures      int
+	numberOfWords          int
+	objectDescription      []string
+	objectOriginalLocation []int
+	roomDescription        []string
+	roomExit               [][]int
+	startingRoom           int
+	timeLimit              int
+	treasureRoomId         int
+	wordLength             int
+
+	// The game state
+	alternateCounter []int
+	alternateRoom    []int
+	counterRegister  int
+	currentRoom      int
+	lastNoun         string
+	nextPartPending  bool
+	objectLocation   []int
+	prngState        int
+	ramSaveSlot      map[string]gameSnapshot
+	statusFlag       []bool
+	turnCounter      int
+	undoHistory      []gameSnapshot
+
+	// The turn being played
+	commandParameter      int
+	commandParameterIndex int
+	contFlag              bool
+	extractedInputWords   []string
+	foundWord             []int
+	globalNoun            string
+	inputAdjectives       []string
+	keyboardInput         string
+	keyboardInput2        string
+	wordSuggestions       []string
+
+	// Where the game is played. The saved games go to saveRoot when it isn't
+	// the directory of the player, such as for games played on a server
+	commandLog     io.Writer // Where the commands played are written, with -o
+	inputReader    *bufio.Reader
+	output         glkDisplay
+	saveRoot       string
+	scanning       bool // Loaded for the library, with nobody to ask anything
+	sessionRunning bool // Played as a session, so the end of the game doesn't end the program
+
+	// Set when the game is a player's part of a shared world
+	player *worldPlayer
+}
+
+// An engine with no game loaded, which shows the game on a display and reads
+// what the player types from the input
+func newGameEngine(display glkDisplay, input io.Reader) *gameEngine {
+	return &gameEngine{
+		gameDialect: DIALECT_SCOTT,
+		inputReader: bufio.NewReader(input),
+		output:      display,
+		prngState:   int(time.Now().UnixNano()) % VALUES_IN_16_BITS,
+		ramSaveSlot: make(map[string]gameSnapshot),
+	}
+}
+
+// An engine for a game played as a session, with its saved games kept in a
+// directory of its own
+func newSessionEngine(display glkDisplay, input io.Reader, saveRoot string) *gameEngine {
+	game := newGameEngine(display, input)
+	game.saveRoot = saveRoot
+	game.sessionRunning = true
+	return game
+}
+
+// Load a game into an engine of its own, ready to be played as a session.
+// Where the commands come from is up to the caller
+func loadSessionGame(fileName string, display glkDisplay, saveRoot string) (*gameEngine, error) {
+	game := newSessionEngine(display, strings.NewReader(""), saveRoot)
+	if err := game.loadGameDataFile(fileName); err != nil {
+		return nil, err
+	}
+	game.gameFile = fileName
+	game.initializeGameState()
+	return game, nil
+}
+
+// Run something in a game played as a session. Returns false if the game
+// came to an end
+func (game *gameEngine) run(play func()) (stillPlaying bool) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			if _, over := recovered.(gameOverSignal); !over {
+				panic(recovered)
+			}
+			stillPlaying = false
+		}
+	}()
+	play()
+	return true
+}
+
+// Wait for something, such as the player typing a command. The other players
+// of a shared world play meanwhile, so the world is brought in again as it is
+// after the wait
+func (game *gameEngine) waitOutsideWorld(wait func()) {
+	player := game.player
+	if player == nil {
+		wait()
+		return
+	}
+	player.leaveWorld()
+	player.world.mutex.Unlock()
+	defer func() {
+		player.world.mutex.Lock()
+		player.enterWorld()
+	}()
+	wait()
+}
+
+// Play the commands in the input, one turn for each line. Lines that are
+// read while a turn is played, such as answers to questions, belong to that
+// turn
+func (game *gameEngine) playCommands(input string) {
+	game.inputReader = bufio.NewReader(strings.NewReader(input))
+	for {
+		line, inputAvailable := game.readInputLine()
+		if !inputAvailable {
+			return
+		}
+		game.playTurn(line, game.takeSnapshot())
+	}
+}
+
+// Play a game the way it's played at a terminal, until the input runs out.
+// Whoever plays it can do something right before and right after each turn,
+// when beforeTurn and afterTurn aren't nil
+func (game *gameEngine) playInteractively(beforeTurn, afterTurn func()) {
+	for {
+		game.updateWindows()
+		fmt.Fprintln(game.output, localize("Tell me what to do"))
+		line, inputAvailable := game.readCommand()
+		fmt.Fprintln(game.output)
+		if !inputAvailable {
+			game.exitGame()
+		}
+
+		// The state is taken after the wait for the command, which a shared
+		// world may have changed
+		turnSnapshot := game.takeSnapshot()
+		if beforeTurn != nil {
+			beforeTurn()
+		}
+		game.playTurn(line, turnSnapshot)
+		if afterTurn != nil {
+			afterTurn()
+		}
+	}
+}
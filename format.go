@@ -0,0 +1,173 @@
+package main
+
+import (
+	"io"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// All game output goes through a formatter that wraps lines at word
+// boundaries, so that nothing is broken in the middle of a word on a narrow
+// terminal. Words inside an item name are joined with non-breaking spaces, so
+// that an item in a list is kept on one line where possible.
+
+const (
+	ESCAPE_CHARACTER   rune = '\033'
+	NON_BREAKING_SPACE rune = '\u00a0'
+)
+
+var (
+	ansiEnabled     = true
+	noANSI          bool
+	outputWidth     int
+	upperCaseOutput bool
+)
+
+type outputFormatter struct {
+	target        io.Writer
+	wrapAt        func() int
+	column        int
+	pendingSpaces int
+	word          []rune
+	escape        []rune
+}
+
+func newOutputFormatter(target io.Writer) *outputFormatter {
+	return &outputFormatter{target: target, wrapAt: wrapWidth}
+}
+
+// Decide how output is shown, from the command line and the terminal
+func (game *gameEngine) setUpOutput() {
+	ansiEnabled = !noANSI && os.Getenv("TERM") != "dumb" && isTerminal(os.Stdout)
+	game.output = terminalDisplay{newOutputFormatter(os.Stdout)}
+}
+
+// Width to wrap at, or 0 when output doesn't go to a terminal of known width
+func wrapWidth() int {
+	if outputWidth > 0 {
+		return outputWidth
+	}
+	if tuiActive {
+		return tuiWidth
+	}
+	if width, _, known := terminalSize(os.Stdout); known {
+		return width
+	}
+	return 0
+}
+
+func (formatter *outputFormatter) Write(text []byte) (int, error) {
+	var formatted strings.Builder
+	width := formatter.wrapAt()
+	for _, character := range string(text) {
+		switch {
+		case len(formatter.escape) > 0 || character == ESCAPE_CHARACTER:
+			formatter.writeWord(&formatted, width)
+			formatter.addEscape(&formatted, character)
+		case character == '\n':
+			formatter.writeWord(&formatted, width)
+			formatted.WriteRune('\n')
+			formatter.column = 0
+			formatter.pendingSpaces = 0
+		case character == ' ':
+			formatter.writeWord(&formatted, width)
+			formatter.pendingSpaces++
+		default:
+			formatter.word = append(formatter.word, character)
+		}
+	}
+	formatter.writeWord(&formatted, width)
+	if _, err := io.WriteString(formatter.target, formatted.String()); err != nil {
+		return 0, err
+	}
+	return len(text), nil
+}
+
+// Put out the word collected so far, on a new line if it doesn't fit on this
+// one. Spaces before a line break are left out
+func (formatter *outputFormatter) writeWord(formatted *strings.Builder, width int) {
+	if len(formatter.word) == 0 {
+		return
+	}
+	word := formatter.word
+	formatter.word = nil
+	if width > 0 && formatter.column > 0 && formatter.column+formatter.pendingSpaces+len(word) > width {
+		formatted.WriteRune('\n')
+		formatter.column = 0
+		formatter.pendingSpaces = 0
+	}
+	formatted.WriteString(strings.Repeat(" ", formatter.pendingSpaces))
+	formatter.column += formatter.pendingSpaces
+	formatter.pendingSpaces = 0
+
+	// A word longer than a whole line has to be broken somewhere
+	for width > 0 && formatter.column+len(word) > width {
+		formatted.WriteString(formatText(string(word[:width-formatter.column])))
+		formatted.WriteRune('\n')
+		word = word[width-formatter.column:]
+		formatter.column = 0
+	}
+	formatted.WriteString(formatText(string(word)))
+	formatter.column += len(word)
+}
+
+// Escape sequences go to the terminal untouched, and take up no room on the
+// line, although clearing the screen starts a new one
+func (formatter *outputFormatter) addEscape(formatted *strings.Builder, character rune) {
+	formatter.escape = append(formatter.escape, character)
+	sequence := formatter.escape
+	finished := len(sequence) == 2 && sequence[1] != '['
+	if len(sequence) > 2 && character >= '@' && character <= '~' {
+		finished = true
+	}
+	if !finished {
+		return
+	}
+	formatted.WriteString(string(sequence))
+	if character == 'H' || character == 'J' {
+		formatter.column = 0
+		formatter.pendingSpaces = 0
+	}
+	formatter.escape = nil
+}
+
+// Text as it should look on screen: upper case if asked for, and with plain
+// spaces where words were only kept together for wrapping
+func formatText(text string) string {
+	text = strings.ReplaceAll(text, string(NON_BREAKING_SPACE), " ")
+	if upperCaseOutput {
+		text = strings.ToUpper(text)
+	}
+	return text
+}
+
+// Keep the words of a text together when wrapping
+func unbreakable(text string) string {
+	return strings.ReplaceAll(text, " ", string(NON_BREAKING_SPACE))
+}
+
+// Break text into lines no longer than width, at spaces where possible
+func wrapLines(text string, width int) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		line := []rune{}
+		for _, word := range strings.Split(paragraph, " ") {
+			wordRunes := []rune(word)
+			if len(line) > 0 && len(line)+1+len(wordRunes) > width {
+				lines = append(lines, formatText(string(line)))
+				line = line[:0]
+			}
+			for len(wordRunes) > width {
+				lines = append(lines, formatText(string(wordRunes[:width])))
+				wordRunes = wordRunes[width:]
+			}
+			if len(line) > 0 {
+				line = append(line, ' ')
+			}
+			line = append(line, wordRunes...)
+		}
+		lines = append(lines, formatText(strings.TrimRightFunc(string(line), unicode.IsSpace)))
+	}
+	return lines
+}
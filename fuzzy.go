@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// With fuzzy matching on, a word that isn't in the vocabulary is compared with
+// the words that are. If only one of them is a single typing mistake away, the
+// word is taken to mean that one. If there are several, the player is asked
+// which one was meant.
+
+const MAX_TYPO_DISTANCE int = 1
+
+var fuzzyMatching bool
+
+// A vocabulary word that is close to a word that was typed
+type closeWord struct {
+	wordId int
+	word   string
+}
+
+// Replace unknown words with the words they were most likely meant to be.
+// Words that could mean more than one thing are left for suggestWords
+func (game *gameEngine) correctTypos() {
+	game.wordSuggestions = nil
+	for verbOrNoun := 0; verbOrNoun <= 1; verbOrNoun++ {
+		input := game.extractedInputWords[verbOrNoun]
+		if game.foundWord[verbOrNoun] > 0 || input == "" {
+			continue
+		}
+		// Objects can be picked up by nouns that aren't in the vocabulary, and
+		// a pronoun with nothing to refer to yet isn't a typing mistake
+		if verbOrNoun == 1 && (game.isObjectNoun(input) || game.isPronoun(input)) {
+			continue
+		}
+
+		matches := game.closeWords(input, verbOrNoun)
+		if len(matches) == 1 {
+			game.foundWord[verbOrNoun] = matches[0].wordId
+			game.extractedInputWords[verbOrNoun] = matches[0].word
+			if verbOrNoun == 1 {
+				game.globalNoun = matches[0].word
+			}
+		} else if len(matches) > 1 {
+			for _, match := range matches {
+				game.wordSuggestions = append(game.wordSuggestions, match.word)
+			}
+		}
+	}
+}
+
+// Ask about the words that could have been meant, if there were any
+func (game *gameEngine) suggestWords() bool {
+	if len(game.wordSuggestions) == 0 {
+		return false
+	}
+	last := len(game.wordSuggestions) - 1
+	if last == 0 {
+		fmt.Fprintf(game.output, localize("Did you mean %s?\n"), game.wordSuggestions[0])
+	} else {
+		fmt.Fprintf(game.output, localize("Did you mean %s or %s?\n"), strings.Join(game.wordSuggestions[:last], ", "), game.wordSuggestions[last])
+	}
+	return true
+}
+
+// The vocabulary words closest to the input, one for each meaning. Synonyms
+// count as the word they are a synonym of
+func (game *gameEngine) closeWords(input string, verbOrNoun int) []closeWord {
+	input = foldCase(extractFirstCharacters(input, game.wordLength))
+	bestDistance := MAX_TYPO_DISTANCE + 1
+	var matches []closeWord
+	found := make(map[int]bool)
+
+	nonSynonym := 0
+	for wordId, words := range game.listOfVerbsAndNouns {
+		word := words[verbOrNoun]
+		if !strings.HasPrefix(word, "*") {
+			nonSynonym = wordId
+		}
+		word = strings.TrimPrefix(word, "*")
+		if nonSynonym == 0 || word == "" || word == "." {
+			continue
+		}
+
+		distance := editDistance(input, foldCase(extractFirstCharacters(word, game.wordLength)))
+		if distance > MAX_TYPO_DISTANCE {
+			continue
+		}
+		if distance < bestDistance {
+			bestDistance = distance
+			matches = nil
+			found = make(map[int]bool)
+		}
+		if distance == bestDistance && !found[nonSynonym] {
+			found[nonSynonym] = true
+			matches = append(matches, closeWord{wordId: nonSynonym, word: strings.ToUpper(word)})
+		}
+	}
+	return matches
+}
+
+func (game *gameEngine) isObjectNoun(input string) bool {
+	for _, description := range game.objectDescription {
+		if noun := objectNoun(description); noun != "" && game.sameWord(noun, input) {
+			return true
+		}
+	}
+	return false
+}
+
+// Number of letters that have to be added, removed, changed or swapped with
+// the next one to turn one word into the other
+func editDistance(first, second string) int {
+	a := []rune(first)
+	b := []rune(second)
+	distance := make([][]int, len(a)+1)
+	for i := range distance {
+		distance[i] = make([]int, len(b)+1)
+		distance[i][0] = i
+	}
+	for j := range distance[0] {
+		distance[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			distance[i][j] = minimum(distance[i-1][j]+1, distance[i][j-1]+1, distance[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				distance[i][j] = minimum(distance[i][j], distance[i-2][j-2]+1)
+			}
+		}
+	}
+	return distance[len(a)][len(b)]
+}
+
+func minimum(values ...int) int {
+	smallest := values[0]
+	for _, value := range values[1:] {
+		if value < smallest {
+			smallest = value
+		}
+	}
+	return smallest
+}
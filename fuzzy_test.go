@@ -0,0 +1,107 @@
+package main
+
+import (
+	"io"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func loadFixture(t *testing.T, fileName string) *gameEngine {
+	t.Helper()
+	game := newGameEngine(terminalDisplay{io.Discard}, strings.NewReader(""))
+	if err := game.loadGameDataFile(filepath.Join("testdata", fileName)); err != nil {
+		t.Fatalf("couldn't load %s: %v", fileName, err)
+	}
+	return game
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		first, second string
+		distance      int
+	}{
+		{"", "", 0},
+		{"LAMP", "LAMP", 0},
+		{"LAMP", "LMAP", 1},
+		{"LAMP", "LAM", 1},
+		{"LAMP", "CLAMP", 1},
+		{"LAMP", "LIMP", 1},
+		{"GOLD", "GLOD", 1},
+		{"ABC", "CBA", 2},
+		{"", "KEY", 3},
+		{"ÖFFNE", "OFFNE", 1},
+	}
+	for _, test := range tests {
+		if distance := editDistance(test.first, test.second); distance != test.distance {
+			t.Errorf("editDistance(%q, %q) = %d, wanted %d", test.first, test.second, distance, test.distance)
+		}
+		if distance := editDistance(test.second, test.first); distance != test.distance {
+			t.Errorf("editDistance(%q, %q) = %d, wanted %d", test.second, test.first, distance, test.distance)
+		}
+	}
+}
+
+// The vocabulary of testdata/adventure.dat has words of 3 letters. GO has the
+// synonyms *WALK, *RUN and *ENTER, GET has *TAKE and *PICK, and COIN has *GOLD
+func TestCloseWords(t *testing.T) {
+	game := loadFixture(t, "adventure.dat")
+	tests := []struct {
+		name       string
+		input      string
+		verbOrNoun int
+		matches    []closeWord
+	}{
+		{"typing mistake", "LOOC", 0, []closeWord{{8, "LOOK"}}},
+		{"swapped letters", "LMAP", 1, []closeWord{{7, "LAMP"}}},
+		{"synonym of a verb", "TAEK", 0, []closeWord{{10, "TAKE"}}},
+		{"synonym of a noun", "GLOD", 1, []closeWord{{13, "GOLD"}}},
+		{"exact word", "SCORE", 0, []closeWord{{13, "SCORE"}}},
+		{"ambiguous verb", "SAE", 0, []closeWord{{5, "SAY"}, {6, "SAVE"}}},
+		{"ambiguous noun", "BOI", 1, []closeWord{{13, "COIN"}, {15, "BOX"}}},
+		{"shorter than the word length", "LA", 1, []closeWord{{7, "LAMP"}}},
+		{"one letter", "O", 0, []closeWord{{1, "GO"}}},
+		{"too far from anything", "XYZZY", 0, nil},
+		{"letters past the word length don't count", "LAMXYZ", 1, []closeWord{{7, "LAMP"}}},
+	}
+	for _, test := range tests {
+		matches := game.closeWords(test.input, test.verbOrNoun)
+		if !reflect.DeepEqual(matches, test.matches) {
+			t.Errorf("%s: closeWords(%q, %d) = %v, wanted %v", test.name, test.input, test.verbOrNoun, matches, test.matches)
+		}
+	}
+}
+
+func TestCorrectTypos(t *testing.T) {
+	game := loadFixture(t, "adventure.dat")
+	fuzzyMatching = true
+	defer func() { fuzzyMatching = false }()
+	tests := []struct {
+		input       string
+		found       []int
+		corrected   []string
+		suggestions []string
+	}{
+		{"TAEK LMAP", []int{10, 7}, []string{"TAKE", "LAMP"}, nil},
+		{"WLAK NROTH", []int{1, 1}, []string{"WALK", "NORTH"}, nil},
+		{"WAKL NORTH", []int{0, 1}, []string{"WAKL", "NORTH"}, []string{"WALK", "TAKE", "WAIT"}},
+		{"GET LA", []int{10, 7}, []string{"GET", "LAMP"}, nil},
+		{"SAE BOI", []int{0, 0}, []string{"SAE", "BOI"}, []string{"SAY", "SAVE", "COIN", "BOX"}},
+		{"XYZZY LAMP", []int{0, 7}, []string{"XYZZY", "LAMP"}, nil},
+		{"GET IT", []int{10, 0}, []string{"GET", "IT"}, nil},
+	}
+	for _, test := range tests {
+		game.keyboardInput2 = test.input
+		game.extractWords()
+		if !reflect.DeepEqual(game.foundWord, test.found) {
+			t.Errorf("%q: found %v, wanted %v", test.input, game.foundWord, test.found)
+		}
+		if !reflect.DeepEqual(game.extractedInputWords[:2], test.corrected) {
+			t.Errorf("%q: words %v, wanted %v", test.input, game.extractedInputWords[:2], test.corrected)
+		}
+		if !reflect.DeepEqual(game.wordSuggestions, test.suggestions) {
+			t.Errorf("%q: suggestions %v, wanted %v", test.input, game.wordSuggestions, test.suggestions)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"time"
+)
+
+// The engine shows everything through a display modelled on Glk, the I/O
+// layer many interactive fiction interpreters are written for. Text goes to
+// the main window in a style, the room description and the status line go to
+// windows of their own on displays that have them, and clearing, pauses and
+// reading a line are left to the display. This way the same engine plays at a
+// terminal, in the split-window mode, for RemGlk front-ends, in a browser and
+// over telnet.
+
+type glkWindow int
+
+const (
+	WINDOW_MAIN glkWindow = iota
+	WINDOW_ROOM
+	WINDOW_STATUS
+)
+
+type glkStyle int
+
+const (
+	STYLE_NORMAL glkStyle = iota
+	STYLE_ALERT
+)
+
+// Names of the styles, as Glk calls them
+var glkStyleName = []string{"normal", "alert"}
+
+type glkDisplay interface {
+	// Text written goes to the main window, in the style last set
+	io.Writer
+	setStyle(style glkStyle)
+
+	hasWindow(window glkWindow) bool
+	drawWindow(window glkWindow, text string)
+	clearWindow(window glkWindow)
+
+	delay(duration time.Duration)
+	readLine(input *bufio.Reader) (string, error)
+}
+
+// Bring the room and status windows up to date, on displays that have them.
+// Nothing is shown in them until a game has been started
+func (game *gameEngine) updateWindows() {
+	if game.statusFlag == nil {
+		return
+	}
+	if game.output.hasWindow(WINDOW_ROOM) {
+		game.output.drawWindow(WINDOW_ROOM, game.roomDescriptionText())
+	}
+	if game.output.hasWindow(WINDOW_STATUS) {
+		game.output.drawWindow(WINDOW_STATUS, game.statusLineText())
+	}
+}
+
+// Show a warning, such as the light running out, so that it stands out
+func (game *gameEngine) printAlert(text string) {
+	game.output.setStyle(STYLE_ALERT)
+	fmt.Fprint(game.output, text)
+	game.output.setStyle(STYLE_NORMAL)
+}
+
+// Terminal codes for the styles
+func styleCode(style glkStyle) string {
+	if style == STYLE_ALERT {
+		return "\033[1m"
+	}
+	return "\033[0m"
+}
+
+// A terminal, or whatever standard output is, with everything in one window
+type terminalDisplay struct {
+	writer io.Writer
+}
+
+func (display terminalDisplay) Write(text []byte) (int, error) {
+	return display.writer.Write(text)
+}
+
+func (display terminalDisplay) setStyle(style glkStyle) {
+	if ansiEnabled {
+		fmt.Fprint(display.writer, styleCode(style))
+	}
+}
+
+func (display terminalDisplay) hasWindow(window glkWindow) bool {
+	return window == WINDOW_MAIN
+}
+
+func (display terminalDisplay) drawWindow(window glkWindow, text string) {}
+
+func (display terminalDisplay) clearWindow(window glkWindow) {
+	if window != WINDOW_MAIN {
+		return
+	}
+	if !ansiEnabled {
+		fmt.Fprintln(display.writer)
+		return
+	}
+	fmt.Fprint(display.writer, "\033[H\033[2J")
+}
+
+func (display terminalDisplay) delay(duration time.Duration) {
+	time.Sleep(duration)
+}
+
+func (display terminalDisplay) readLine(input *bufio.Reader) (string, error) {
+	return input.ReadString('\n')
+}
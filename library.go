@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	LIBRARY_FILE_NAME     string = "library.json"
+	LIBRARY_MAX_FILE_SIZE int64  = 4 << 20
+	METADATA_EXTENSION    string = ".json"
+)
+
+var libraryDirectory string
+
+// What the launcher shows about a game file
+type libraryEntry struct {
+	path              string
+	title             string
+	adventureNumber   int
+	adventureVersion  int
+	numberOfRooms     int
+	numberOfObjects   int
+	numberOfTreasures int
+	saveSlots         int
+	lastPlayed        time.Time
+}
+
+// Optional file next to a game file, named like the game file with .json
+// added, that gives the game a proper title
+type gameMetadata struct {
+	Title string `json:"title"`
+}
+
+// Games started from the launcher, and when they were last played
+type libraryState struct {
+	LastPlayed map[string]time.Time `json:"lastPlayed"`
+}
+
+func libraryStateFile() (string, error) {
+	directory, err := dataDirectory()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(directory, LIBRARY_FILE_NAME), nil
+}
+
+func readLibraryState() libraryState {
+	state := libraryState{LastPlayed: make(map[string]time.Time)}
+	stateFile, err := libraryStateFile()
+	if err != nil {
+		return state
+	}
+	data, err := ioutil.ReadFile(stateFile)
+	if err != nil {
+		return state
+	}
+	json.Unmarshal(data, &state)
+	if state.LastPlayed == nil {
+		state.LastPlayed = make(map[string]time.Time)
+	}
+	return state
+}
+
+// Remember that a game was started, so that the launcher can show it first
+func recordLastPlayed(gameFile string) error {
+	path, err := filepath.Abs(gameFile)
+	if err != nil {
+		return err
+	}
+	state := readLibraryState()
+	state.LastPlayed[path] = time.Now()
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	stateFile, err := libraryStateFile()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(stateFile), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(stateFile, data, 0644)
+}
+
+// The title comes from the metadata file if there is one, otherwise from the
+// room the game starts in
+func (game *gameEngine) gameTitle(path string) string {
+	data, err := ioutil.ReadFile(path + METADATA_EXTENSION)
+	if err == nil {
+		var metadata gameMetadata
+		if json.Unmarshal(data, &metadata) == nil && metadata.Title != "" {
+			return metadata.Title
+		}
+	}
+	return game.roomName(game.startingRoom)
+}
+
+// Load every file in a directory that can be played, and note what is in it
+func scanLibrary(directory string) ([]libraryEntry, error) {
+	files, err := os.ReadDir(directory)
+	if err != nil {
+		return nil, err
+	}
+	state := readLibraryState()
+
+	var entries []libraryEntry
+	for _, file := range files {
+		name := file.Name()
+		extension := strings.ToLower(filepath.Ext(name))
+		if file.IsDir() || strings.HasPrefix(name, ".") || extension == METADATA_EXTENSION || extension == SAVE_FILE_EXTENSION {
+			continue
+		}
+		info, err := file.Info()
+		if err != nil || !info.Mode().IsRegular() || info.Size() > LIBRARY_MAX_FILE_SIZE {
+			continue
+		}
+
+		// Each game is loaded into an engine of its own, which mustn't stop
+		// to ask which member of an archive to use
+		path := filepath.Join(directory, name)
+		game := newGameEngine(terminalDisplay{ioutil.Discard}, strings.NewReader(""))
+		game.scanning = true
+		if game.loadGameDataFile(path) != nil {
+			continue
+		}
+		entry := libraryEntry{
+			path:              path,
+			title:             game.gameTitle(path),
+			adventureNumber:   game.adventureNumber,
+			adventureVersion:  game.adventureVersion,
+			numberOfRooms:     game.numberOfRooms,
+			numberOfObjects:   game.numberOfObjects,
+			numberOfTreasures: game.numberOfTreasures,
+		}
+		if saveDirectory, err := game.saveDirectory(); err == nil {
+			if slots, err := saveSlotFiles(saveDirectory); err == nil {
+				entry.saveSlots = len(slots)
+			}
+		}
+		if absolutePath, err := filepath.Abs(path); err == nil {
+			entry.lastPlayed = state.LastPlayed[absolutePath]
+		}
+		entries = append(entries, entry)
+	}
+
+	// Most recently played games first, then the rest by file name
+	sort.SliceStable(entries, func(i, j int) bool {
+		if !entries[i].lastPlayed.Equal(entries[j].lastPlayed) {
+			return entries[i].lastPlayed.After(entries[j].lastPlayed)
+		}
+		return entries[i].path < entries[j].path
+	})
+	return entries, nil
+}
+
+// Show the games in the library directory and let the player choose one.
+// Returns an empty name if there is nothing to play
+func (game *gameEngine) chooseFromLibrary() (string, error) {
+	directory := libraryDirectory
+	if directory == "" {
+		directory = "."
+	}
+	entries, err := scanLibrary(directory)
+	if err != nil {
+		return "", err
+	}
+	if len(entries) == 0 {
+		return "", errors.New("no games found in " + directory)
+	}
+
+	fmt.Fprintf(game.output, localize("Games in %s:\n\n"), directory)
+	fmt.Fprintf(game.output, "%3s  %-30s %-20s %5s %6s %5s %7s %5s  %s\n",
+		"", localize("Title"), localize("File"), localize("Adv"), localize("Rooms"), localize("Items"),
+		localize("Treas."), localize("Saves"), localize("Last played"))
+	for number, entry := range entries {
+		lastPlayed := ""
+		if !entry.lastPlayed.IsZero() {
+			lastPlayed = entry.lastPlayed.Format("2006-01-02 15:04")
+		}
+		fmt.Fprintf(game.output, "%3d. %-30.30s %-20.20s %5s %6d %5d %7d %5d  %s\n", number+1, entry.title,
+			filepath.Base(entry.path), fmt.Sprintf("%d.%d", entry.adventureNumber, entry.adventureVersion),
+			entry.numberOfRooms, entry.numberOfObjects, entry.numberOfTreasures, entry.saveSlots, lastPlayed)
+	}
+	fmt.Fprintln(game.output)
+
+	for {
+		fmt.Fprintf(game.output, localize("Which game do you want to play? (1-%d, Enter for 1, Q to quit)\n"), len(entries))
+		answer, inputAvailable := game.readInputLine()
+		answer = strings.TrimSpace(answer)
+		if !inputAvailable || strings.EqualFold(answer, "Q") {
+			return "", nil
+		}
+		if answer == "" {
+			return entries[0].path, nil
+		}
+		number, err := strconv.Atoi(answer)
+		if err == nil && number >= 1 && number <= len(entries) {
+			return entries[number-1].path, nil
+		}
+	}
+}
@@ -0,0 +1,408 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// When commands are typed at a terminal, the line can be edited with the
+// arrow keys, earlier commands are brought back with up and down, and Tab
+// completes words from the vocabulary of the game. The commands are kept
+// between games, in a history file for each adventure.
+
+const (
+	HISTORY_FILE_NAME string = "history"
+	HISTORY_LINES     int    = 500
+)
+
+const (
+	KEY_CTRL_A    rune = 1
+	KEY_CTRL_B    rune = 2
+	KEY_CTRL_C    rune = 3
+	KEY_CTRL_D    rune = 4
+	KEY_CTRL_E    rune = 5
+	KEY_CTRL_F    rune = 6
+	KEY_BACKSPACE rune = 8
+	KEY_TAB       rune = 9
+	KEY_NEWLINE   rune = 10
+	KEY_CTRL_K    rune = 11
+	KEY_ENTER     rune = 13
+	KEY_CTRL_N    rune = 14
+	KEY_CTRL_P    rune = 16
+	KEY_CTRL_U    rune = 21
+	KEY_ESCAPE    rune = 27
+	KEY_DELETE    rune = 127
+)
+
+var (
+	commandHistory []string
+	inputSource    *os.File = os.Stdin
+	rawModeFailed  bool
+)
+
+// The line editor needs a terminal to read keys from, and to be able to move
+// the cursor on the screen
+func (game *gameEngine) lineEditingAvailable() bool {
+	return !game.sessionRunning && !rawModeFailed && ansiEnabled && inputSource != nil && isTerminal(inputSource)
+}
+
+// Read a command at the prompt. Commands typed at a terminal are kept in the
+// history, and commands are written to the file given with -o
+func (game *gameEngine) readCommand() (string, bool) {
+	var input string
+	var inputAvailable bool
+	if game.lineEditingAvailable() {
+		input, inputAvailable = game.editLine(true)
+		if inputAvailable {
+			game.addToHistory(input)
+		}
+	} else {
+		input, inputAvailable = game.readInputLine()
+	}
+	if tuiActive {
+		recordTUIInput(input)
+	}
+	if inputAvailable && game.commandLog != nil {
+		fmt.Fprintln(game.commandLog, input)
+	}
+	return input, inputAvailable
+}
+
+func (game *gameEngine) historyFile() (string, error) {
+	directory, err := game.saveDirectory()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(directory, HISTORY_FILE_NAME), nil
+}
+
+// Read the commands from earlier games of the loaded adventure
+func (game *gameEngine) loadHistory() {
+	commandHistory = nil
+	path, err := game.historyFile()
+	if err != nil {
+		return
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if scanner.Text() != "" {
+			commandHistory = append(commandHistory, scanner.Text())
+		}
+	}
+	if len(commandHistory) > HISTORY_LINES {
+		commandHistory = commandHistory[len(commandHistory)-HISTORY_LINES:]
+		game.writeHistory()
+	}
+}
+
+func (game *gameEngine) writeHistory() error {
+	path, err := game.historyFile()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, []byte(strings.Join(commandHistory, "\n")+"\n"), 0644)
+}
+
+// Remember a command, unless it's empty or the same as the one before
+func (game *gameEngine) addToHistory(input string) {
+	input = strings.TrimSpace(input)
+	if input == "" || (len(commandHistory) > 0 && commandHistory[len(commandHistory)-1] == input) {
+		return
+	}
+	commandHistory = append(commandHistory, input)
+
+	path, err := game.historyFile()
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	fmt.Fprintln(file, input)
+}
+
+// State of the line being edited
+type lineEditor struct {
+	game         *gameEngine
+	line         []rune
+	cursor       int
+	historyIndex int
+	savedLine    []rune
+	commands     bool
+	lastWasTab   bool
+}
+
+// Read a line from the terminal, handling the editing keys. With commands
+// set, the history and completion are available as well
+func (game *gameEngine) editLine(commands bool) (string, bool) {
+	restoreTerminal, rawMode := enableRawMode(inputSource)
+	if !rawMode {
+		// Lines are read as they come from then on
+		rawModeFailed = true
+		return game.readInputLine()
+	}
+	defer restoreTerminal()
+
+	editor := lineEditor{game: game, historyIndex: len(commandHistory), commands: commands}
+	for {
+		key, _, err := game.inputReader.ReadRune()
+		if err == io.EOF && len(editor.line) == 0 {
+			fmt.Fprint(os.Stdout, "\r\n")
+			return "", false
+		} else if err != nil {
+			fmt.Fprint(os.Stdout, "\r\n")
+			return string(editor.line), true
+		}
+		wasTab := editor.lastWasTab
+		editor.lastWasTab = false
+
+		switch key {
+		case KEY_ENTER, KEY_NEWLINE:
+			fmt.Fprint(os.Stdout, "\r\n")
+			return string(editor.line), true
+		case KEY_CTRL_C:
+			// Raw mode doesn't turn Ctrl-C into a signal, so pass it on
+			restoreTerminal()
+			fmt.Fprint(os.Stdout, "^C\r\n")
+			if process, err := os.FindProcess(os.Getpid()); err == nil {
+				process.Signal(os.Interrupt)
+			}
+			return "", false
+		case KEY_CTRL_D:
+			if len(editor.line) == 0 {
+				fmt.Fprint(os.Stdout, "\r\n")
+				return "", false
+			}
+			editor.deleteForward()
+		case KEY_BACKSPACE, KEY_DELETE:
+			editor.deleteBackward()
+		case KEY_CTRL_A:
+			editor.cursor = 0
+		case KEY_CTRL_E:
+			editor.cursor = len(editor.line)
+		case KEY_CTRL_B:
+			editor.moveCursor(-1)
+		case KEY_CTRL_F:
+			editor.moveCursor(1)
+		case KEY_CTRL_K:
+			editor.line = editor.line[:editor.cursor]
+		case KEY_CTRL_U:
+			editor.line = editor.line[editor.cursor:]
+			editor.cursor = 0
+		case KEY_CTRL_P:
+			editor.showHistory(-1)
+		case KEY_CTRL_N:
+			editor.showHistory(1)
+		case KEY_TAB:
+			if editor.commands {
+				editor.complete(wasTab)
+				editor.lastWasTab = true
+			}
+		case KEY_ESCAPE:
+			editor.handleEscapeSequence()
+		default:
+			if key >= ' ' {
+				editor.insert(key)
+			}
+		}
+		editor.redraw()
+	}
+}
+
+// Arrow keys and the like come as escape sequences, such as ESC [ A for up
+func (editor *lineEditor) handleEscapeSequence() {
+	introducer, _, err := editor.game.inputReader.ReadRune()
+	if err != nil || (introducer != '[' && introducer != 'O') {
+		return
+	}
+	var parameter []rune
+	for {
+		key, _, err := editor.game.inputReader.ReadRune()
+		if err != nil {
+			return
+		}
+		if key >= '0' && key <= '9' || key == ';' {
+			parameter = append(parameter, key)
+			continue
+		}
+		switch {
+		case key == 'A':
+			editor.showHistory(-1)
+		case key == 'B':
+			editor.showHistory(1)
+		case key == 'C':
+			editor.moveCursor(1)
+		case key == 'D':
+			editor.moveCursor(-1)
+		case key == 'H' || (key == '~' && (string(parameter) == "1" || string(parameter) == "7")):
+			editor.cursor = 0
+		case key == 'F' || (key == '~' && (string(parameter) == "4" || string(parameter) == "8")):
+			editor.cursor = len(editor.line)
+		case key == '~' && string(parameter) == "3":
+			editor.deleteForward()
+		}
+		return
+	}
+}
+
+func (editor *lineEditor) insert(key rune) {
+	editor.line = append(editor.line[:editor.cursor], append([]rune{key}, editor.line[editor.cursor:]...)...)
+	editor.cursor++
+}
+
+func (editor *lineEditor) deleteBackward() {
+	if editor.cursor > 0 {
+		editor.line = append(editor.line[:editor.cursor-1], editor.line[editor.cursor:]...)
+		editor.cursor--
+	}
+}
+
+func (editor *lineEditor) deleteForward() {
+	if editor.cursor < len(editor.line) {
+		editor.line = append(editor.line[:editor.cursor], editor.line[editor.cursor+1:]...)
+	}
+}
+
+func (editor *lineEditor) moveCursor(distance int) {
+	editor.cursor += distance
+	if editor.cursor < 0 {
+		editor.cursor = 0
+	} else if editor.cursor > len(editor.line) {
+		editor.cursor = len(editor.line)
+	}
+}
+
+// Step through the history. The line being typed is kept, so that stepping
+// past the newest command brings it back
+func (editor *lineEditor) showHistory(step int) {
+	if !editor.commands {
+		return
+	}
+	index := editor.historyIndex + step
+	if index < 0 || index > len(commandHistory) {
+		return
+	}
+	if editor.historyIndex == len(commandHistory) {
+		editor.savedLine = append([]rune(nil), editor.line...)
+	}
+	editor.historyIndex = index
+	if index == len(commandHistory) {
+		editor.line = append([]rune(nil), editor.savedLine...)
+	} else {
+		editor.line = []rune(commandHistory[index])
+	}
+	editor.cursor = len(editor.line)
+}
+
+// Complete the word before the cursor. If more than one word fits, the
+// common part is filled in, and pressing Tab again lists them
+func (editor *lineEditor) complete(listCandidates bool) {
+	start := editor.cursor
+	for start > 0 && editor.line[start-1] != ' ' {
+		start--
+	}
+	firstWord := strings.TrimSpace(string(editor.line[:start])) == ""
+	prefix := strings.ToUpper(string(editor.line[start:editor.cursor]))
+
+	var candidates []string
+	for _, word := range editor.game.completionWords(firstWord) {
+		if strings.HasPrefix(word, prefix) {
+			candidates = append(candidates, word)
+		}
+	}
+	if len(candidates) == 0 {
+		return
+	}
+
+	completion := []rune(candidates[0])
+	for _, candidate := range candidates[1:] {
+		common := 0
+		for common < len(completion) && common < len([]rune(candidate)) && []rune(candidate)[common] == completion[common] {
+			common++
+		}
+		completion = completion[:common]
+	}
+	if len(candidates) == 1 {
+		completion = append(completion, ' ')
+	}
+	// The word is written the way the game knows it
+	progress := len(completion) > len([]rune(prefix))
+	editor.line = append(append(append([]rune(nil), editor.line[:start]...), completion...), editor.line[editor.cursor:]...)
+	editor.cursor = start + len(completion)
+
+	if len(candidates) > 1 && !progress && listCandidates {
+		fmt.Fprintf(os.Stdout, "\r\n%s\r\n", strings.Join(candidates, "  "))
+	}
+}
+
+// Words that can be completed: verbs at the start of the command, and after
+// that the nouns of the game and the objects that can be seen
+func (game *gameEngine) completionWords(verbs bool) []string {
+	found := make(map[string]bool)
+	for _, words := range game.listOfVerbsAndNouns {
+		word := words[1]
+		if verbs {
+			word = words[0]
+		}
+		word = strings.ToUpper(strings.TrimPrefix(word, "*"))
+		if word != "" && word != "." {
+			found[word] = true
+		}
+	}
+	if !verbs {
+		for object, description := range game.objectDescription {
+			location := game.objectLocation[object]
+			if location != game.currentRoom && location != ROOM_INVENTORY {
+				continue
+			}
+			if noun := objectNoun(description); noun != "" {
+				found[noun] = true
+			}
+		}
+	}
+
+	var words []string
+	for word := range found {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+	return words
+}
+
+// The noun an object can be picked up with, from the /NOUN/ at the end of its
+// description
+func objectNoun(description string) string {
+	start := strings.Index(description, "/")
+	end := strings.LastIndex(description, "/")
+	if start < 0 || end <= start {
+		return ""
+	}
+	return strings.ToUpper(description[start+1 : end])
+}
+
+// Draw the line again, and put the cursor where it should be
+func (editor *lineEditor) redraw() {
+	fmt.Fprintf(os.Stdout, "\r%s\033[K", string(editor.line))
+	if back := len(editor.line) - editor.cursor; back > 0 {
+		fmt.Fprintf(os.Stdout, "\033[%dD", back)
+	}
+}
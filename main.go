@@ -2,13 +2,16 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"errors"
 	"flag"
 	"fmt"
+	"hash/crc32"
 	"io"
-	"io/ioutil"
 	"log"
 	"os"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -25,6 +28,7 @@ const (
 	CONDITION_DIVISOR        int     = 20
 	CONDITIONS               int     = 5
 	COUNTER_TIME_LIMIT       int     = 8
+	DEFAULT_UNDO_DEPTH       int     = 10
 	DIRECTION_NOUNS          int     = 6
 	FALSE_VALUE              int     = 0
 	FLAG_LAMP_EMPTY          int     = 16
@@ -38,6 +42,7 @@ const (
 	PERCENT_UNITS            int     = 100
 	PRNG_PRIME               int     = 65537
 	PRNG_PRM                 int     = 75
+	RAM_SAVE_SLOTS           int     = 10
 	REALLY_BIG_NUMBER        int     = 32767
 	ROOM_INVENTORY           int     = -1
 	ROOM_STORE               int     = 0
@@ -49,56 +54,31 @@ const (
 	VERB_GO                  int     = 1
 )
 
-var directionNounText = []string{"NORTH", "SOUTH", "EAST", "WEST", "UP", "DOWN"}
+const introMessage = `
+                 *** Welcome ***
 
-var (
-	actionData              [][]int
-	actionDescription       []string
-	adventureNumber         int
-	adventureVersion        int
-	alternateCounter        []int
-	alternateRoom           []int
-	carriedObjects          int
-	commandInHandle         string
-	commandOrDisplayMessage int
-	commandOutHandle        string
-	commandParameter        int
-	commandParameterIndex   int
-	contFlag                bool
-	counterRegister         int
-	currentRoom             int
-	extractedInputWords     []string
-	flagDebug               bool
-	foundWord               []int
-	gameBytes               int
-	gameFile                string
-	globalNoun              string
-	keyboardInput           string
-	keyboardInput2          string
-	listOfVerbsAndNouns     [][]string
-	maxObjectsCarried       int
-	message                 []string
-	numberOfActions         int
-	numberOfMessages        int
-	numberOfObjects         int
-	numberOfRooms           int
-	numberOfTreasures       int
-	numberOfWords           int
-	objectDescription       []string
-	objectLocation          []int
-	objectOriginalLocation  []int
-	roomDescription         []string
-	roomExit                [][]int
-	startingRoom            int
-	statusFlag              []bool
-	storedTreasures         int
-	timeLimit               int
-	treasureRoomId          int
-	wordLength              int
-)
+ Unless told differently you must find *TREASURES* 
+and-return-them-to-their-proper--place!
+
+I'm your puppet. Give me english commands that
+consist of a noun and verb. Some examples...
+
+To find out what you're carrying you might say: TAKE INVENTORY 
+to go into a hole you might say: GO HOLE 
+to save current game: SAVE GAME
+
+You will at times need special items to do things: But I'm 
+sure you'll be a good adventurer and figure these things out.
+
+     Happy adventuring... Hit enter to start`
+
+var directionNounText = []string{"NORTH", "SOUTH", "EAST", "WEST", "UP", "DOWN"}
 
 var (
-	prngState = int(time.Now().Unix()) % VALUES_IN_16_BITS
+	commandInHandle  string
+	commandOutHandle string
+	flagDebug        bool
+	undoDepth        = DEFAULT_UNDO_DEPTH
 )
 
 var conditionName = []string{
@@ -114,66 +94,78 @@ var commandName = []string{
 	"CLS", "SAVE", "EXx,x", "CONT", "AGETx", "BYx<-x",
 	"DspRM", "CT-1", "DspCT", "CT<-n", "EXRM0", "EXm,CT",
 	"CT+n", "CT-n", "SAYw", "SAYwCR", "SAYCR", "EXc,CR",
-	"DELAY",
+	"DELAY", "PIC", "WAIT", "NEXT",
+}
+
+// gameSnapshot holds all the game state that can change during a turn
+type gameSnapshot struct {
+	currentRoom      int
+	alternateRoom    []int
+	counterRegister  int
+	alternateCounter []int
+	objectLocation   []int
+	statusFlag       []bool
+	prngState        int
+	turnCounter      int
 }
 
-type conditionFunc func(int) bool
+type conditionFunc func(*gameEngine, int) bool
 
 var conditionFunction []conditionFunc = []conditionFunc{
 
 	// 0 Par
-	func(parameter int) bool {
+	func(game *gameEngine, parameter int) bool {
 		return true
 	},
 
 	// 1 HAS
-	func(parameter int) bool {
-		return objectLocation[parameter] == ROOM_INVENTORY
+	func(game *gameEngine, parameter int) bool {
+		return game.objectLocation[parameter] == ROOM_INVENTORY
 	},
 
 	// 2 IN/W
-	func(parameter int) bool {
-		return objectLocation[parameter] == currentRoom
+	func(game *gameEngine, parameter int) bool {
+		return game.objectLocation[parameter] == game.currentRoom
 	},
 
 	// 3 AVL
-	func(parameter int) bool {
-		return objectLocation[parameter] == ROOM_INVENTORY || objectLocation[parameter] == currentRoom
+	func(game *gameEngine, parameter int) bool {
+		return game.objectLocation[parameter] == ROOM_INVENTORY || game.objectLocation[parameter] == game.currentRoom
 	},
 
 	// 4 IN
-	func(parameter int) bool {
-		return currentRoom == parameter
+	func(game *gameEngine, parameter int) bool {
+		return game.currentRoom == parameter
 	},
 
 	// 5 -IN/W
-	func(parameter int) bool {
-		return objectLocation[parameter] != currentRoom
+	func(game *gameEngine, parameter int) bool {
+		return game.objectLocation[parameter] != game.currentRoom
 	},
 
 	// 6 -HAVE
-	func(parameter int) bool {
-		return objectLocation[parameter] != ROOM_INVENTORY
+	func(game *gameEngine, parameter int) bool {
+		return game.objectLocation[parameter] != ROOM_INVENTORY
 	},
 
 	// 7 -IN
-	func(parameter int) bool {
-		return currentRoom != parameter
+	func(game *gameEngine, parameter int) bool {
+		return game.currentRoom != parameter
 	},
 
 	// 8 BIT
-	func(parameter int) bool {
-		return statusFlag[parameter]
+	func(game *gameEngine, parameter int) bool {
+		return game.statusFlag[parameter]
 	},
 
 	// 9 -BIT
-	func(parameter int) bool {
-		return !statusFlag[parameter]
+	func(game *gameEngine, parameter int) bool {
+		return !game.statusFlag[parameter]
 	},
 
 	// 10 ANY
-	func(parameter int) bool {
-		for _, location := range objectLocation {
+	func(game *gameEngine, parameter int) bool {
+		for _, location := range game.objectLocation {
 			if location == ROOM_INVENTORY {
 				return true
 			}
@@ -182,8 +174,8 @@ var conditionFunction []conditionFunc = []conditionFunc{
 	},
 
 	// 11 -ANY
-	func(parameter int) bool {
-		for _, location := range objectLocation {
+	func(game *gameEngine, parameter int) bool {
+		for _, location := range game.objectLocation {
 			if location == ROOM_INVENTORY {
 				return false
 			}
@@ -192,436 +184,638 @@ var conditionFunction []conditionFunc = []conditionFunc{
 	},
 
 	// 12 -AVL
-	func(parameter int) bool {
-		return !(objectLocation[parameter] == ROOM_INVENTORY || objectLocation[parameter] == currentRoom)
+	func(game *gameEngine, parameter int) bool {
+		return !(game.objectLocation[parameter] == ROOM_INVENTORY || game.objectLocation[parameter] == game.currentRoom)
 	},
 
 	// 13 -RM0
-	func(parameter int) bool {
-		return objectLocation[parameter] != ROOM_STORE
+	func(game *gameEngine, parameter int) bool {
+		return game.objectLocation[parameter] != ROOM_STORE
 	},
 
 	// 14 RM0
-	func(parameter int) bool {
-		return objectLocation[parameter] == ROOM_STORE
+	func(game *gameEngine, parameter int) bool {
+		return game.objectLocation[parameter] == ROOM_STORE
 	},
 
 	// 15 CT<=
-	func(parameter int) bool {
-		return counterRegister <= parameter
+	func(game *gameEngine, parameter int) bool {
+		return game.counterRegister <= parameter
 	},
 
 	// 16 CT>
-	func(parameter int) bool {
-		return counterRegister > parameter
+	func(game *gameEngine, parameter int) bool {
+		return game.counterRegister > parameter
 	},
 
 	// 17 ORIG
-	func(parameter int) bool {
-		return objectLocation[parameter] == objectLocation[parameter]
+	func(game *gameEngine, parameter int) bool {
+		return game.objectLocation[parameter] == game.objectLocation[parameter]
 	},
 
 	// 18 -ORIG
-	func(parameter int) bool {
-		return objectLocation[parameter] != objectLocation[parameter]
+	func(game *gameEngine, parameter int) bool {
+		return game.objectLocation[parameter] != game.objectLocation[parameter]
 	},
 
 	// 19 CT=
-	func(parameter int) bool {
-		return counterRegister == parameter
+	func(game *gameEngine, parameter int) bool {
+		return game.counterRegister == parameter
 	},
 }
 
-type commandFunc func(*int, *bool)
+type commandFunc func(*gameEngine, *int, *bool)
 
 var commandFunction []commandFunc = []commandFunc{
 	// 0 GETx
-	func(actionId *int, continueExecutingCommands *bool) {
+	func(game *gameEngine, actionId *int, continueExecutingCommands *bool) {
 		carriedObjects := 0
 
-		for _, location := range objectLocation {
+		for _, location := range game.objectLocation {
 			if location == ROOM_INVENTORY {
 				carriedObjects++
 			}
 		}
-		if carriedObjects >= maxObjectsCarried {
-			fmt.Println("I've too much too carry. try -take inventory-")
+		if carriedObjects >= game.maxObjectsCarried {
+			fmt.Fprintln(game.output, localize("I've too much too carry. try -take inventory-"))
 			*continueExecutingCommands = false
 		}
-		getCommandParameter(*actionId)
-		objectLocation[commandParameter] = ROOM_INVENTORY
+		game.getCommandParameter(*actionId)
+		game.objectLocation[game.commandParameter] = ROOM_INVENTORY
 	},
 
 	// 1 DROPx
-	func(actionId *int, continueExecutingCommands *bool) {
-		getCommandParameter(*actionId)
-		objectLocation[commandParameter] = currentRoom
+	func(game *gameEngine, actionId *int, continueExecutingCommands *bool) {
+		game.getCommandParameter(*actionId)
+		game.objectLocation[game.commandParameter] = game.currentRoom
 	},
 
 	// 2 GOTOy
-	func(actionId *int, continueExecutingCommands *bool) {
-		getCommandParameter(*actionId)
-		currentRoom = commandParameter
+	func(game *gameEngine, actionId *int, continueExecutingCommands *bool) {
+		game.getCommandParameter(*actionId)
+		game.currentRoom = game.commandParameter
 	},
 
 	// 3 x->RM0
-	func(actionId *int, continueExecutingCommands *bool) {
-		getCommandParameter(*actionId)
-		objectLocation[commandParameter] = 0
+	func(game *gameEngine, actionId *int, continueExecutingCommands *bool) {
+		game.getCommandParameter(*actionId)
+		game.objectLocation[game.commandParameter] = 0
 	},
 
 	// 4 NIGHT
-	func(actionId *int, continueExecutingCommands *bool) {
-		statusFlag[FLAG_NIGHT] = true
+	func(game *gameEngine, actionId *int, continueExecutingCommands *bool) {
+		game.statusFlag[FLAG_NIGHT] = true
 	},
 
 	// 5 DAY
-	func(actionId *int, continueExecutingCommands *bool) {
-		statusFlag[FLAG_NIGHT] = false
+	func(game *gameEngine, actionId *int, continueExecutingCommands *bool) {
+		game.statusFlag[FLAG_NIGHT] = false
 	},
 
 	// 6 SETz
-	func(actionId *int, continueExecutingCommands *bool) {
-		getCommandParameter(*actionId)
-		statusFlag[commandParameter] = true
+	func(game *gameEngine, actionId *int, continueExecutingCommands *bool) {
+		game.getCommandParameter(*actionId)
+		game.statusFlag[game.commandParameter] = true
 	},
 
 	// 7 x->RM0
-	func(actionId *int, continueExecutingCommands *bool) {
-		getCommandParameter(*actionId)
-		objectLocation[commandParameter] = 0
+	func(game *gameEngine, actionId *int, continueExecutingCommands *bool) {
+		game.getCommandParameter(*actionId)
+		game.objectLocation[game.commandParameter] = 0
 	},
 
 	// 8 CLRz
-	func(actionId *int, continueExecutingCommands *bool) {
-		getCommandParameter(*actionId)
-		statusFlag[commandParameter] = false
+	func(game *gameEngine, actionId *int, continueExecutingCommands *bool) {
+		game.getCommandParameter(*actionId)
+		game.statusFlag[game.commandParameter] = false
 	},
 
 	// 9 DEAD
-	func(actionId *int, continueExecutingCommands *bool) {
-		fmt.Println("I'm dead...")
-		currentRoom = numberOfRooms
-		statusFlag[FLAG_NIGHT] = false
-		showRoomDescription()
+	func(game *gameEngine, actionId *int, continueExecutingCommands *bool) {
+		game.printAlert(localize("I'm dead...") + "\n")
+		game.currentRoom = game.numberOfRooms
+		game.statusFlag[FLAG_NIGHT] = false
+		game.showRoomDescription()
 	},
 
 	// 10 x->y
-	func(actionId *int, continueExecutingCommands *bool) {
-		getCommandParameter(*actionId)
-		temporary1 := commandParameter
-		getCommandParameter(*actionId)
-		objectLocation[temporary1] = commandParameter
+	func(game *gameEngine, actionId *int, continueExecutingCommands *bool) {
+		game.getCommandParameter(*actionId)
+		temporary1 := game.commandParameter
+		game.getCommandParameter(*actionId)
+		game.objectLocation[temporary1] = game.commandParameter
 	},
 
 	// 11 FINI
-	func(actionId *int, continueExecutingCommands *bool) {
-		os.Exit(0)
+	func(game *gameEngine, actionId *int, continueExecutingCommands *bool) {
+		game.endGame()
 	},
 
 	// 12 DspRM
-	func(actionId *int, continueExecutingCommands *bool) {
-		showRoomDescription()
+	func(game *gameEngine, actionId *int, continueExecutingCommands *bool) {
+		game.showRoomDescription()
 	},
 
 	// 13 SCORE
-	func(actionId *int, continueExecutingCommands *bool) {
-		storedTreasures := 0
-		for object, location := range objectLocation {
-			if location == treasureRoomId {
-				if strings.HasPrefix(objectDescription[object], "*") {
-					storedTreasures++
-				}
-			}
-		}
-		scoreMsg := fmt.Sprintf("I've stored %d treasures. ON A SCALE OF 0 TO %d THAT RATES A %d\n",
+	func(game *gameEngine, actionId *int, continueExecutingCommands *bool) {
+		storedTreasures := game.countStoredTreasures()
+		scoreMsg := fmt.Sprintf(localize("I've stored %d treasures. ON A SCALE OF 0 TO %d THAT RATES A %d\n"),
 			storedTreasures, PERCENT_UNITS,
-			int(float64(storedTreasures)/float64(numberOfTreasures)*float64(PERCENT_UNITS)))
-		if _, err := fmt.Print(scoreMsg); err != nil {
+			int(float64(storedTreasures)/float64(game.numberOfTreasures)*float64(PERCENT_UNITS)))
+		if _, err := fmt.Fprint(game.output, scoreMsg); err != nil {
 			log.Fatal(err)
 		}
-		if storedTreasures == numberOfTreasures {
-			fmt.Println("Well done.")
-			os.Exit(0)
+		if storedTreasures == game.numberOfTreasures {
+			fmt.Fprintln(game.output, localize("Well done."))
+			game.endGame()
 		}
 	},
 
 	// 14 INV
-	func(actionId *int, continueExecutingCommands *bool) {
-		carryingNothingText := "Nothing"
-		objectText := ""
-		for object, location := range objectLocation {
-			if location != ROOM_INVENTORY {
-				continue
-			} else {
-				objectText = stripNounFromObjectDescription(object)
-			}
-			if _, err := fmt.Print(objectText, ". "); err != nil {
-				log.Fatal(err)
+	func(game *gameEngine, actionId *int, continueExecutingCommands *bool) {
+		var carried []string
+		for object, location := range game.objectLocation {
+			if location == ROOM_INVENTORY {
+				carried = append(carried, game.stripNounFromObjectDescription(object))
 			}
-			carryingNothingText = ""
 		}
-		if _, err := fmt.Print(carryingNothingText, "\n\n"); err != nil {
+		carryingText := localize("Nothing")
+		if len(carried) > 0 {
+			carryingText = itemList(carried)
+		}
+		if _, err := fmt.Fprint(game.output, carryingText, "\n\n"); err != nil {
 			log.Fatal(err)
 		}
 	},
 
 	// 15 SET0
-	func(actionId *int, continueExecutingCommands *bool) {
-		commandParameter = 0
-		statusFlag[commandParameter] = true
+	func(game *gameEngine, actionId *int, continueExecutingCommands *bool) {
+		game.commandParameter = 0
+		game.statusFlag[game.commandParameter] = true
 	},
 
 	// 16 CLR0
-	func(actionId *int, continueExecutingCommands *bool) {
-		commandParameter = 0
-		statusFlag[commandParameter] = false
+	func(game *gameEngine, actionId *int, continueExecutingCommands *bool) {
+		game.commandParameter = 0
+		game.statusFlag[game.commandParameter] = false
 	},
 
 	// 17 FILL
-	func(actionId *int, continueExecutingCommands *bool) {
-		alternateCounter[COUNTER_TIME_LIMIT] = timeLimit
-		objectLocation[LIGHT_SOURCE_ID] = ROOM_INVENTORY
-		statusFlag[FLAG_LAMP_EMPTY] = false
+	func(game *gameEngine, actionId *int, continueExecutingCommands *bool) {
+		game.alternateCounter[COUNTER_TIME_LIMIT] = game.timeLimit
+		game.objectLocation[LIGHT_SOURCE_ID] = ROOM_INVENTORY
+		game.statusFlag[FLAG_LAMP_EMPTY] = false
 	},
 
 	// 18 CLS
-	func(actionId *int, continueExecutingCommands *bool) {
-		cls()
+	func(game *gameEngine, actionId *int, continueExecutingCommands *bool) {
+		game.cls()
 	},
 
 	// 19 SAVE
-	func(actionId *int, continueExecutingCommands *bool) {
-		saveGame()
+	func(game *gameEngine, actionId *int, continueExecutingCommands *bool) {
+		game.saveGame()
 	},
 
 	// 20 EXx,x
-	func(actionId *int, continueExecutingCommands *bool) {
-		getCommandParameter(*actionId)
-		temporary1 := commandParameter
-		getCommandParameter(*actionId)
-		temporary2 := objectLocation[commandParameter]
-		objectLocation[commandParameter] = objectLocation[temporary1]
-		objectLocation[temporary1] = temporary2
+	func(game *gameEngine, actionId *int, continueExecutingCommands *bool) {
+		game.getCommandParameter(*actionId)
+		temporary1 := game.commandParameter
+		game.getCommandParameter(*actionId)
+		temporary2 := game.objectLocation[game.commandParameter]
+		game.objectLocation[game.commandParameter] = game.objectLocation[temporary1]
+		game.objectLocation[temporary1] = temporary2
 	},
 
 	// 21 CONT
-	func(actionId *int, continueExecutingCommands *bool) {
-		contFlag = true
+	func(game *gameEngine, actionId *int, continueExecutingCommands *bool) {
+		game.contFlag = true
 	},
 
 	// 22 AGETx
-	func(actionId *int, continueExecutingCommands *bool) {
-		getCommandParameter(*actionId)
-		objectLocation[commandParameter] = ROOM_INVENTORY
+	func(game *gameEngine, actionId *int, continueExecutingCommands *bool) {
+		game.getCommandParameter(*actionId)
+		game.objectLocation[game.commandParameter] = ROOM_INVENTORY
 	},
 
 	// 23 BYx<-x
-	func(actionId *int, continueExecutingCommands *bool) {
-		getCommandParameter(*actionId)
-		firstObject := commandParameter
-		getCommandParameter(*actionId)
-		secondObject := commandParameter
-		objectLocation[firstObject] = objectLocation[secondObject]
+	func(game *gameEngine, actionId *int, continueExecutingCommands *bool) {
+		game.getCommandParameter(*actionId)
+		firstObject := game.commandParameter
+		game.getCommandParameter(*actionId)
+		secondObject := game.commandParameter
+		game.objectLocation[firstObject] = game.objectLocation[secondObject]
 	},
 
 	// 24 DspRM
-	func(actionId *int, continueExecutingCommands *bool) {
-		showRoomDescription()
+	func(game *gameEngine, actionId *int, continueExecutingCommands *bool) {
+		game.showRoomDescription()
 	},
 
 	// 25 CT-1
-	func(actionId *int, continueExecutingCommands *bool) {
-		counterRegister--
+	func(game *gameEngine, actionId *int, continueExecutingCommands *bool) {
+		game.counterRegister--
 	},
 
 	// 26 DspCT
-	func(actionId *int, continueExecutingCommands *bool) {
-		if _, err := fmt.Print(counterRegister); err != nil {
+	func(game *gameEngine, actionId *int, continueExecutingCommands *bool) {
+		if _, err := fmt.Fprint(game.output, game.counterRegister); err != nil {
 			log.Fatal(err)
 		}
 	},
 
 	// 27 CT<-n
-	func(actionId *int, continueExecutingCommands *bool) {
-		getCommandParameter(*actionId)
-		counterRegister = commandParameter
+	func(game *gameEngine, actionId *int, continueExecutingCommands *bool) {
+		game.getCommandParameter(*actionId)
+		game.counterRegister = game.commandParameter
 	},
 
 	// 28 EXRM0
-	func(actionId *int, continueExecutingCommands *bool) {
-		temp := currentRoom
-		currentRoom = alternateRoom[0]
-		alternateRoom[0] = temp
+	func(game *gameEngine, actionId *int, continueExecutingCommands *bool) {
+		temp := game.currentRoom
+		game.currentRoom = game.alternateRoom[0]
+		game.alternateRoom[0] = temp
 	},
 
 	// 29 EXm,CT
-	func(actionId *int, continueExecutingCommands *bool) {
-		getCommandParameter(*actionId)
-		temp := counterRegister
-		counterRegister = alternateCounter[commandParameter]
-		alternateCounter[commandParameter] = temp
+	func(game *gameEngine, actionId *int, continueExecutingCommands *bool) {
+		game.getCommandParameter(*actionId)
+		temp := game.counterRegister
+		game.counterRegister = game.alternateCounter[game.commandParameter]
+		game.alternateCounter[game.commandParameter] = temp
 	},
 
 	// 30 CT+n
-	func(actionId *int, continueExecutingCommands *bool) {
-		getCommandParameter(*actionId)
-		counterRegister += commandParameter
+	func(game *gameEngine, actionId *int, continueExecutingCommands *bool) {
+		game.getCommandParameter(*actionId)
+		game.counterRegister += game.commandParameter
 	},
 
 	// 31 CT-n
-	func(actionId *int, continueExecutingCommands *bool) {
-		getCommandParameter(*actionId)
-		counterRegister -= commandParameter
-		if counterRegister < MINIMUM_COUNTER_VALUE {
-			counterRegister = MINIMUM_COUNTER_VALUE
+	func(game *gameEngine, actionId *int, continueExecutingCommands *bool) {
+		game.getCommandParameter(*actionId)
+		game.counterRegister -= game.commandParameter
+		if game.counterRegister < MINIMUM_COUNTER_VALUE {
+			game.counterRegister = MINIMUM_COUNTER_VALUE
 		}
 	},
 
 	// 32 SAYw
-	func(actionId *int, continueExecutingCommands *bool) {
-		if _, err := fmt.Print(globalNoun); err != nil {
+	func(game *gameEngine, actionId *int, continueExecutingCommands *bool) {
+		if _, err := fmt.Fprint(game.output, game.globalNoun); err != nil {
 			log.Fatal(err)
 		}
 	},
 
 	// 33 SAYwCR
-	func(actionId *int, continueExecutingCommands *bool) {
-		if _, err := fmt.Print(globalNoun, "\n"); err != nil {
+	func(game *gameEngine, actionId *int, continueExecutingCommands *bool) {
+		if _, err := fmt.Fprint(game.output, game.globalNoun, "\n"); err != nil {
 			log.Fatal(err)
 		}
 	},
 
 	// 34 SAYCR
-	func(actionId *int, continueExecutingCommands *bool) {
-		if _, err := fmt.Print("\n"); err != nil {
+	func(game *gameEngine, actionId *int, continueExecutingCommands *bool) {
+		if _, err := fmt.Fprint(game.output, "\n"); err != nil {
 			log.Fatal(err)
 		}
 	},
 
 	// 35 EXc,CR
-	func(actionId *int, continueExecutingCommands *bool) {
-		getCommandParameter(*actionId)
-		temp := currentRoom
-		currentRoom = alternateRoom[commandParameter]
-		alternateRoom[commandParameter] = temp
+	func(game *gameEngine, actionId *int, continueExecutingCommands *bool) {
+		game.getCommandParameter(*actionId)
+		temp := game.currentRoom
+		game.currentRoom = game.alternateRoom[game.commandParameter]
+		game.alternateRoom[game.commandParameter] = temp
 	},
 
 	// 36 DELAY
-	func(actionId *int, continueExecutingCommands *bool) {
-		time.Sleep(1 * time.Second)
+	func(game *gameEngine, actionId *int, continueExecutingCommands *bool) {
+		game.waitOutsideWorld(func() {
+			game.output.delay(1 * time.Second)
+		})
+	},
+
+	// 37 PIC
+	func(game *gameEngine, actionId *int, continueExecutingCommands *bool) {
+		// Command 89 in the data, which ScottFree skips along with its
+		// parameter, as the later graphic games draw picture number
+		// parameter with it. There are no pictures to draw here either
+		game.getCommandParameter(*actionId)
+	},
+
+	// The following commands are only used by the Brian Howarth dialect.
+	// ScottFree has nothing past PIC, so their numbers are this interpreter's
+	// own
+
+	// 38 WAIT
+	func(game *gameEngine, actionId *int, continueExecutingCommands *bool) {
+		fmt.Fprintln(game.output, localize("Press Enter to continue"))
+		game.readInputLine()
+	},
+
+	// 39 NEXT
+	func(game *gameEngine, actionId *int, continueExecutingCommands *bool) {
+		// The next part is loaded when the turn is over
+		*continueExecutingCommands = false
+		game.nextPartPending = true
 	},
 }
 
 func main() {
-	// Get commandline options
-	commandOrDisplayMessage = 0
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		serve(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "telnet" {
+		serveTelnet(os.Args[2:])
+		return
+	}
 
-	// Load game data file, if specified
-	var gameFile string
-	argsWithoutProg := os.Args[1:]
+	// Get commandline options
+	game := newGameEngine(terminalDisplay{os.Stdout}, os.Stdin)
+	inHandle, debug := game.commandlineOptions()
+	flagDebug = debug
+	game.setUpOutput()
+	setNoiseWords(noiseWordList)
+	if err := selectLanguage(); err != nil {
+		fmt.Fprintln(game.output, err)
+		os.Exit(1)
+	}
 
-	if len(argsWithoutProg) > 0 {
-		gameFile = argsWithoutProg[0]
-		loadGameDataFile(gameFile)
+	// Load game data file, if specified, or let the player pick one from the
+	// library directory
+	if flag.NArg() > 0 {
+		game.gameFile = flag.Arg(0)
 	} else {
-		commandlineHelp()
+		var err error
+		game.gameFile, err = game.chooseFromLibrary()
+		if err != nil && libraryDirectory == "" {
+			game.commandlineHelp()
+		} else if err != nil {
+			fmt.Fprintln(game.output, err)
+			os.Exit(1)
+		} else if game.gameFile == "" {
+			os.Exit(0)
+		}
 	}
-	// Initialize values
-	currentRoom = startingRoom
-
-	// Prepare the rest of the variables
-	alternateRoom = make([]int, ALTERNATE_ROOM_REGISTERS)
-	alternateCounter = make([]int, ALTERNATE_COUNTERS)
-	counterRegister = 0
-	statusFlag = make([]bool, STATUS_FLAGS)
-	statusFlag[FLAG_NIGHT] = false
-	alternateCounter[COUNTER_TIME_LIMIT] = timeLimit
+	if err := game.loadGameDataFile(game.gameFile); err != nil {
+		fmt.Fprintf(game.output, localize("Couldn't load \"%s\": %v\n"), game.gameFile, err)
+		os.Exit(1)
+	}
+	if game.gameFile == STDIN_FILE_NAME && inHandle == os.Stdin {
+		game.useTerminalForInput()
+	} else if game.gameFile != STDIN_FILE_NAME {
+		recordLastPlayed(game.gameFile)
+	}
+	game.initializeGameState()
+	game.loadHistory()
+	if remglkMode {
+		if err := game.startRemGlk(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	} else if tuiMode && !ansiEnabled {
+		fmt.Fprintln(game.output, localize("The split-window mode needs a terminal that understands control codes"))
+	} else if tuiMode {
+		game.startTUI()
+	}
+	game.handleSignals()
 
-	showIntro()
-	showRoomDescription()
+	if game.offerRecovery() {
+		game.cls()
+		game.showRoomDescription()
+	} else {
+		game.showIntro()
+		game.startGame()
+	}
 
-	foundWord = []int{0, 0}
-	runActions(foundWord[0], 0)
+	// Keep the state between turns for recovery, and autosave it now and then
+	checkpoint := func() {
+		setRecoveryPoint(game.takeSnapshot())
+		game.autosaveTurn()
+	}
+	checkpoint()
+	game.playInteractively(nil, checkpoint)
+}
 
-	// Create a new Scanner
-	scanner := bufio.NewScanner(os.Stdin)
+// Carry out a command, either one of the interpreter's own or one for the
+// game. The snapshot is the state before the command, for undoing it
+func (game *gameEngine) playTurn(input string, turnSnapshot gameSnapshot) {
+	game.keyboardInput2 = input
+	loadMatch, _ := regexp.MatchString(`(?i)^\s*LOAD\s*GAME`, game.keyboardInput2)
+	undoMatch, _ := regexp.MatchString(`(?i)^\s*UNDO\s*$`, game.keyboardInput2)
+	restartMatch, _ := regexp.MatchString(`(?i)^\s*RESTART\s*$`, game.keyboardInput2)
+	quitMatch, _ := regexp.MatchString(`(?i)^\s*QUIT\s*$`, game.keyboardInput2)
+	savesMatch, _ := regexp.MatchString(`(?i)^\s*SAVES\s*$`, game.keyboardInput2)
+	saveRamMatch := regexp.MustCompile(`(?i)^\s*SAVE\s*RAM\s*(\S*)\s*$`).FindStringSubmatch(game.keyboardInput2)
+	restoreRamMatch := regexp.MustCompile(`(?i)^\s*(?:RESTORE|LOAD)\s*RAM\s*(\S*)\s*$`).FindStringSubmatch(game.keyboardInput2)
+
+	if game.inSharedWorld() && (loadMatch || undoMatch || restartMatch || saveRamMatch != nil || restoreRamMatch != nil) {
+		fmt.Fprintln(game.output, localize("That can't be done in a shared world"))
+	} else if loadMatch {
+		if game.loadGame() {
+			game.pushUndoSnapshot(turnSnapshot)
+			game.showRoomDescription()
+		}
+	} else if undoMatch {
+		if game.undoTurn() {
+			game.showRoomDescription()
+		}
+	} else if restartMatch {
+		if game.confirm(localize("Do you really want to restart the game? (Y/N)")) {
+			game.pushUndoSnapshot(turnSnapshot)
+			game.initializeGameState()
+			game.cls()
+			game.startGame()
+		}
+	} else if savesMatch {
+		game.listSaveSlots()
+	} else if saveRamMatch != nil {
+		game.saveRam(saveRamMatch[1])
+	} else if restoreRamMatch != nil {
+		if game.restoreRam(restoreRamMatch[1]) {
+			game.pushUndoSnapshot(turnSnapshot)
+			game.showRoomDescription()
+		}
+	} else if quitMatch {
+		if game.confirm(localize("Do you really want to quit? (Y/N)")) {
+			game.exitGame()
+		}
+	} else {
+		game.extractWords()
 
-	//  Main keyboard command input loop
-	for {
+		undefinedWordsFound := (game.foundWord[0] < 1) ||
+			(len(game.extractedInputWords[1]) > 0) && (game.foundWord[1] < 1)
 
-		fmt.Println("Tell me what to do")
+		if (game.foundWord[0] == VERB_CARRY) || (game.foundWord[0] == VERB_DROP) {
+			undefinedWordsFound = false
+		}
 
-		// Wait for the user to enter a command
-		scanner.Scan()
+		if undefinedWordsFound {
+			if !game.suggestWords() {
+				fmt.Fprintln(game.output, localize("You use word(s) I don't know"))
+			}
+		} else {
+			game.pushUndoSnapshot(turnSnapshot)
+			game.rememberNoun()
+			game.turnCounter++
+			game.runActions(game.foundWord[0], game.foundWord[1])
+			game.checkAndChangeLightSourceStatus()
+			game.foundWord[0] = 0
+			game.runActions(game.foundWord[0], game.foundWord[1])
+		}
+	}
+	if game.nextPartPending {
+		game.loadNextPart()
+	}
+}
 
-		keyboardInput2 = scanner.Text()
-		fmt.Println()
+// Set up the game state as it is at the start of a new game
+func (game *gameEngine) initializeGameState() {
+	game.currentRoom = game.startingRoom
+	game.objectLocation = append([]int(nil), game.objectOriginalLocation...)
 
-		match, _ := regexp.MatchString(`(?i)^\s*LOAD\s*GAME`, keyboardInput2)
+	// Prepare the rest of the variables
+	game.alternateRoom = make([]int, ALTERNATE_ROOM_REGISTERS)
+	game.alternateCounter = make([]int, ALTERNATE_COUNTERS)
+	game.counterRegister = 0
+	game.statusFlag = make([]bool, STATUS_FLAGS)
+	game.statusFlag[FLAG_NIGHT] = false
+	game.alternateCounter[COUNTER_TIME_LIMIT] = game.timeLimit
+	game.turnCounter = 0
+	game.lastNoun = ""
+}
 
-		if match {
-			if loadGame() {
-				showRoomDescription()
-			}
-		} else {
-			extractWords()
+// Show the first room and run the automatic actions before the first command
+func (game *gameEngine) startGame() {
+	game.showRoomDescription()
 
-			undefinedWordsFound := (foundWord[0] < 1) ||
-				(len(extractedInputWords[1]) > 0) && (foundWord[1] < 1)
+	game.foundWord = []int{0, 0}
+	game.runActions(game.foundWord[0], 0)
+}
 
-			if (foundWord[0] == VERB_CARRY) || (foundWord[0] == VERB_DROP) {
-				undefinedWordsFound = false
-			}
+func (game *gameEngine) getPrn() int {
+	game.prngState = (PRNG_PRM * (game.prngState + 1) % PRNG_PRIME) % VALUES_IN_16_BITS
+	return game.prngState % PERCENT_UNITS
+}
 
-			if undefinedWordsFound {
-				fmt.Println("You use word(s) I don't know")
-			} else {
-				runActions(foundWord[0], foundWord[1])
-				checkAndChangeLightSourceStatus()
-				foundWord[0] = 0
-				runActions(foundWord[0], foundWord[1])
-			}
-		}
+func (game *gameEngine) getCommandInput() string {
+	input, err := game.readInputString()
+	if err != nil && err != io.EOF {
+		panic(err)
 	}
+	return input
 }
 
-func getPrn() int {
-	prngState = (PRNG_PRM * (prngState + 1) % PRNG_PRIME) % VALUES_IN_16_BITS
-	return prngState % PERCENT_UNITS
+// Read up to the end of the line, wherever the display takes input from.
+// The windows are brought up to date first, for the player to see what the
+// question is about
+func (game *gameEngine) readInputString() (input string, err error) {
+	game.updateWindows()
+	game.waitOutsideWorld(func() {
+		input, err = game.output.readLine(game.inputReader)
+	})
+	return input, err
 }
 
-var inputReader *bufio.Reader = bufio.NewReader(os.Stdin)
-
-func getCommandInput() string {
-	input, err := inputReader.ReadString('\n')
+// Read a line of input, returning false when there is no more input
+func (game *gameEngine) readInputLine() (string, bool) {
+	if game.lineEditingAvailable() {
+		return game.editLine(false)
+	}
+	input, err := game.readInputString()
 	if err != nil && err != io.EOF {
 		panic(err)
 	}
-	return input
+	if err == io.EOF && input == "" {
+		return "", false
+	}
+	return strings.TrimRight(input, "\r\n"), true
 }
 
-func commandlineHelp() {
-	fmt.Println(`
-Usage: GoVerbYourNoun [OPTION]... game_data_file
+// Ask a yes/no question, where anything other than yes counts as no
+func (game *gameEngine) confirm(question string) bool {
+	fmt.Fprintln(game.output, question)
+	answer, _ := game.readInputLine()
+	fmt.Fprintln(game.output)
+	answer = strings.ToUpper(strings.TrimSpace(answer))
+	return strings.HasPrefix(answer, "Y") || strings.HasPrefix(answer, localize("Y"))
+}
+
+func (game *gameEngine) commandlineHelp() {
+	fmt.Fprintln(game.output, `
+Usage: GoVerbYourNoun [OPTION]... [game_data_file]
+  or:  GoVerbYourNoun serve [--listen ADDRESS] [--library DIRECTORY]
+  or:  GoVerbYourNoun telnet [--listen ADDRESS] [--library DIRECTORY | --game FILE]
 Scott Adams adventure game interpreter
 
+Without a game data file, the games in the current directory (or the one given
+with --library) are listed to choose from.
+
+The game data file may be compressed with gzip or packed in a zip archive.
+Use - to read it from standard input.
+
 -i, --input    Command input file
 -o, --output   Command output file
 -d, --debug    Show game debugging info
--h, --help     Display this help and exit`)
+-h, --help     Display this help and exit
+--undo-depth   Number of turns that can be undone (default 10, 0 disables UNDO)
+--autosave     Autosave every N turns, and when quitting or interrupted (default 0, off)
+--dialect      Game engine dialect: auto, scott or howarth (default auto)
+--library      Directory to list games from when no game data file is given
+--tui          Show the room in a fixed window above the scrolling messages
+--remglk       Talk to a front-end in the RemGlk JSON protocol on standard input and output
+--width        Wrap output at this many columns (default: the terminal width)
+--no-ansi      Don't use terminal control codes, for dumb terminals and pipes
+--uppercase    Show all output in upper case, like the original interpreters
+--encoding     Encoding of text game files: auto, utf-8, latin1 or cp437 (default auto)
+--lang         Language of the interpreter messages: en or de (default from the locale)
+--fuzzy        Understand words with small typing mistakes, or suggest what was meant
+--noise-words  Comma separated words to leave out of commands (default THE,A,AN,TO,AT)
+
+Options of serve:
+--listen           Address to listen on (default localhost:8080)
+--library          Directory with the games that can be played (default .)
+--max-sessions     Number of games that can be played at once (default 100)
+--allow-origins    Comma separated origins of other sites whose pages may play, or * for any
+--idle-timeout     End sessions that get no requests for this long (default 15m)
+--lang, --fuzzy    As above
+
+Options of telnet:
+--listen           Address to listen on (default localhost:2323)
+--library          Directory with the games players can choose from (default .)
+--game             Game to play, instead of letting players choose
+--shared           Let everyone play the game given with --game together, in one world
+--max-connections  Number of players that can be connected at once (default 20)
+--idle-timeout     Disconnect players who don't type anything for this long (default 15m)
+--width            Wrap output at this many columns (default 80, 0 doesn't wrap)
+--no-ansi, --lang, --fuzzy  As above`)
 	os.Exit(0)
 }
 
-func commandlineOptions() (*os.File, *os.File, bool) {
+func (game *gameEngine) commandlineOptions() (*os.File, bool) {
 	inputFile := flag.String("i", "", "Command input file")
 	outputFile := flag.String("o", "", "Command output file")
 	debug := flag.Bool("d", false, "Show game debugging info")
 	help := flag.Bool("h", false, "Display this help and exit")
+	flag.IntVar(&undoDepth, "undo-depth", DEFAULT_UNDO_DEPTH, "Number of turns that can be undone")
+	flag.IntVar(&autosaveInterval, "autosave", 0, "Autosave every N turns")
+	flag.StringVar(&selectedDialect, "dialect", DIALECT_AUTO, "Game engine dialect: auto, scott or howarth")
+	flag.StringVar(&libraryDirectory, "library", "", "Directory to list games from when no game data file is given")
+	flag.BoolVar(&tuiMode, "tui", false, "Show the room in a fixed window above the scrolling messages")
+	flag.BoolVar(&remglkMode, "remglk", false, "Talk to a front-end in the RemGlk JSON protocol on standard input and output")
+	flag.IntVar(&outputWidth, "width", 0, "Wrap output at this many columns")
+	flag.BoolVar(&noANSI, "no-ansi", false, "Don't use terminal control codes")
+	flag.BoolVar(&upperCaseOutput, "uppercase", false, "Show all output in upper case")
+	flag.StringVar(&gameFileEncoding, "encoding", ENCODING_AUTO, "Encoding of text game files: auto, utf-8, latin1 or cp437")
+	flag.StringVar(&selectedLanguage, "lang", "", "Language of the interpreter messages: en or de")
+	flag.BoolVar(&fuzzyMatching, "fuzzy", false, "Understand words with small typing mistakes")
+	flag.StringVar(&noiseWordList, "noise-words", DEFAULT_NOISE_WORDS, "Comma separated words to leave out of commands")
 	flag.Parse()
 
 	if *help {
-		commandlineHelp()
+		game.commandlineHelp()
 	}
 
 	var inHandle *os.File = os.Stdin
@@ -631,159 +825,173 @@ func commandlineOptions() (*os.File, *os.File, bool) {
 		if err != nil {
 			panic(fmt.Sprintf("file \"%s\" not found", *inputFile))
 		}
-		inputReader = bufio.NewReader(inHandle)
+		inputSource = inHandle
+		game.inputReader = bufio.NewReader(inHandle)
 	}
 
-	var outHandle *os.File
 	if *outputFile != "" {
-		var err error
-		outHandle, err = os.OpenFile(*outputFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+		outHandle, err := os.OpenFile(*outputFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
 		if err != nil {
 			panic(err)
 		}
+		game.commandLog = outHandle
 	}
 
-	return inHandle, outHandle, *debug
+	return inHandle, *debug
+}
+
+// Items separated like the original interpreters do it, with each item kept
+// on one line when the text is wrapped
+func itemList(items []string) string {
+	for i, item := range items {
+		items[i] = unbreakable(item) + "."
+	}
+	return strings.Join(items, " ")
 }
 
-func stripNounFromObjectDescription(objectNumber int) string {
-	strippedText := objectDescription[objectNumber]
+func (game *gameEngine) stripNounFromObjectDescription(objectNumber int) string {
+	strippedText := game.objectDescription[objectNumber]
 	re := regexp.MustCompile(`/.*/`)
 	strippedText = re.ReplaceAllString(strippedText, "")
 	return strippedText
 }
 
-func checkAndChangeLightSourceStatus() int {
-	if objectLocation[LIGHT_SOURCE_ID] == ROOM_INVENTORY {
-		alternateCounter[COUNTER_TIME_LIMIT]--
-		if alternateCounter[COUNTER_TIME_LIMIT] < 0 {
-			fmt.Println("Light has run out")
-			objectLocation[LIGHT_SOURCE_ID] = 0
-		} else if alternateCounter[COUNTER_TIME_LIMIT] < LIGHT_WARNING_THRESHOLD {
-			fmt.Printf("Light runs out in %d turns!\n", alternateCounter[COUNTER_TIME_LIMIT])
+// Number of treasures in the treasure room
+func (game *gameEngine) countStoredTreasures() int {
+	storedTreasures := 0
+	for object, location := range game.objectLocation {
+		if location == game.treasureRoomId {
+			if strings.HasPrefix(game.objectDescription[object], "*") {
+				storedTreasures++
+			}
 		}
 	}
-	return 1
+	return storedTreasures
 }
 
-func showIntro() int {
-	cls() // Clear screen commented out for debugging reasons
-	introMessage := `
-                 *** Welcome ***
-
- Unless told differently you must find *TREASURES* 
-and-return-them-to-their-proper--place!
-
-I'm your puppet. Give me english commands that
-consist of a noun and verb. Some examples...
-
-To find out what you're carrying you might say: TAKE INVENTORY 
-to go into a hole you might say: GO HOLE 
-to save current game: SAVE GAME
+func (game *gameEngine) checkAndChangeLightSourceStatus() int {
+	if game.objectLocation[LIGHT_SOURCE_ID] == ROOM_INVENTORY {
+		game.alternateCounter[COUNTER_TIME_LIMIT]--
+		if game.alternateCounter[COUNTER_TIME_LIMIT] < 0 {
+			game.printAlert(localize("Light has run out") + "\n")
+			game.objectLocation[LIGHT_SOURCE_ID] = 0
+		} else if game.alternateCounter[COUNTER_TIME_LIMIT] < LIGHT_WARNING_THRESHOLD {
+			game.printAlert(fmt.Sprintf(localize("Light runs out in %d turns!\n"), game.alternateCounter[COUNTER_TIME_LIMIT]))
+		}
+	}
+	return 1
+}
 
-You will at times need special items to do things: But I'm 
-sure you'll be a good adventurer and figure these things out.
+func (game *gameEngine) showIntro() int {
+	game.cls() // Clear screen commented out for debugging reasons
+	fmt.Fprintln(game.output, localize(introMessage))
 
-     Happy adventuring... Hit enter to start`
-	fmt.Println(introMessage)
+	game.keyboardInput = game.getCommandInput()
+	game.cls()
+	return 1
+}
 
-	keyboardInput = getCommandInput()
-	cls()
+func (game *gameEngine) showRoomDescription() int {
+	if game.output.hasWindow(WINDOW_ROOM) {
+		game.updateWindows()
+		return 1
+	}
+	fmt.Fprint(game.output, game.roomDescriptionText())
 	return 1
 }
 
-func showRoomDescription() int {
-	if statusFlag[FLAG_NIGHT] != false {
-		if objectLocation[LIGHT_SOURCE_ID] != ROOM_INVENTORY && objectLocation[LIGHT_SOURCE_ID] != currentRoom {
-			fmt.Println("I can't see: Its too dark.")
-			return 1
+// Describe the current room, with the objects in it and the exits
+func (game *gameEngine) roomDescriptionText() string {
+	var text strings.Builder
+	if game.statusFlag[FLAG_NIGHT] != false {
+		if game.objectLocation[LIGHT_SOURCE_ID] != ROOM_INVENTORY && game.objectLocation[LIGHT_SOURCE_ID] != game.currentRoom {
+			fmt.Fprintln(&text, localize("I can't see: Its too dark."))
+			return text.String()
 		}
 	}
 
-	if strings.HasPrefix(roomDescription[currentRoom], "*") {
-		fmt.Println(roomDescription[currentRoom][1:])
+	if strings.HasPrefix(game.roomDescription[game.currentRoom], "*") {
+		fmt.Fprintln(&text, game.roomDescription[game.currentRoom][1:])
 	} else {
-		fmt.Printf("I'm in a %s", roomDescription[currentRoom])
+		fmt.Fprintf(&text, localize("I'm in a %s"), game.roomDescription[game.currentRoom])
 	}
 
-	objectsFound := false
-	for i, location := range objectLocation {
-		if location == currentRoom {
-			if !objectsFound {
-				fmt.Print(". Visible items here: \n")
-				objectsFound = true
-			}
-			fmt.Printf("%s. ", stripNounFromObjectDescription(i))
+	var items []string
+	for i, location := range game.objectLocation {
+		if location == game.currentRoom {
+			items = append(items, game.stripNounFromObjectDescription(i))
 		}
 	}
-	fmt.Println()
+	if len(items) > 0 {
+		fmt.Fprint(&text, localize(". Visible items here: \n"), itemList(items))
+	}
+	fmt.Fprintln(&text)
 
-	exitFound := false
-	for i, exit := range roomExit[currentRoom] {
+	var exits []string
+	for i, exit := range game.roomExit[game.currentRoom] {
 		if exit != 0 {
-			if !exitFound {
-				fmt.Print("Obvious exits: ")
-				exitFound = true
-			}
-			fmt.Printf("%s ", directionNounText[i])
+			exits = append(exits, localize(directionNounText[i]))
 		}
 	}
-	fmt.Print("\n\n")
-	return 1
+	if len(exits) > 0 {
+		fmt.Fprintf(&text, localize("Obvious exits: %s"), strings.Join(exits, " "))
+	}
+	fmt.Fprint(&text, "\n\n")
+	return text.String()
 }
 
-func handleGoVerb() int {
-	roomDark := statusFlag[FLAG_NIGHT]
+func (game *gameEngine) handleGoVerb() int {
+	roomDark := game.statusFlag[FLAG_NIGHT]
 	if roomDark {
-		roomDark = objectLocation[LIGHT_SOURCE_ID] != currentRoom && objectLocation[LIGHT_SOURCE_ID] != 1
+		roomDark = game.objectLocation[LIGHT_SOURCE_ID] != game.currentRoom && game.objectLocation[LIGHT_SOURCE_ID] != 1
 		if roomDark {
-			fmt.Println("Dangerous to move in the dark!")
+			game.printAlert(localize("Dangerous to move in the dark!") + "\n")
 		}
 	}
 
-	if foundWord[1] < 1 {
-		fmt.Println("Give me a direction too.")
+	if game.foundWord[1] < 1 {
+		fmt.Fprintln(game.output, localize("Give me a direction too."))
 		return 1
 	}
 
-	directionDestination := roomExit[currentRoom][foundWord[1]-1]
+	directionDestination := game.roomExit[game.currentRoom][game.foundWord[1]-1]
 	if directionDestination < 1 {
 		if roomDark {
-			fmt.Println("I fell down and broke my neck.")
-			directionDestination = numberOfRooms
-			statusFlag[FLAG_NIGHT] = false
+			game.printAlert(localize("I fell down and broke my neck.") + "\n")
+			directionDestination = game.numberOfRooms
+			game.statusFlag[FLAG_NIGHT] = false
 		} else {
-			fmt.Println("I can't go in that direction")
+			fmt.Fprintln(game.output, localize("I can't go in that direction"))
 			return 1
 		}
 	}
 
-	currentRoom = directionDestination
-	showRoomDescription()
+	game.currentRoom = directionDestination
+	game.showRoomDescription()
 	return 1
 }
 
 // Get command parameter from the condition section
-func getCommandParameter(currentAction int) (int, error) {
+func (game *gameEngine) getCommandParameter(currentAction int) (int, error) {
 	var conditionCode int = 1
 	for conditionCode != PAR_CONDITION_CODE {
-		conditionLine := actionData[currentAction][commandParameterIndex]
-		commandParameter = int(conditionLine / CONDITION_DIVISOR)
-		conditionCode = conditionLine - commandParameter*CONDITION_DIVISOR
-		commandParameterIndex++
+		conditionLine := game.actionData[currentAction][game.commandParameterIndex]
+		game.commandParameter = int(conditionLine / CONDITION_DIVISOR)
+		conditionCode = conditionLine - game.commandParameter*CONDITION_DIVISOR
+		game.commandParameterIndex++
 	}
 
 	return 1, nil
 }
 
-func decodeCommandFromData(commandNumber, actionId int) int {
+func (game *gameEngine) decodeCommandFromData(commandNumber, actionId int) int {
 	mergedCommandIndex := int(commandNumber/2 + ACTION_COMMAND_OFFSET)
 	var commandCode int
 	// Even or odd command number?
 	if commandNumber%2 != 0 {
-		commandCode = actionData[actionId][mergedCommandIndex] - int(actionData[actionId][mergedCommandIndex]/COMMAND_CODE_DIVISOR)*COMMAND_CODE_DIVISOR
+		commandCode = game.actionData[actionId][mergedCommandIndex] - int(game.actionData[actionId][mergedCommandIndex]/COMMAND_CODE_DIVISOR)*COMMAND_CODE_DIVISOR
 	} else {
-		commandCode = int(actionData[actionId][mergedCommandIndex] / COMMAND_CODE_DIVISOR)
+		commandCode = int(game.actionData[actionId][mergedCommandIndex] / COMMAND_CODE_DIVISOR)
 	}
 	return commandCode
 }
@@ -808,16 +1016,55 @@ func normalizeNewlines(input string, desiredNewline string) string {
 	return strings.Replace(input, "\n", desiredNewline, -1) // Convert to desired newline
 }
 
-func loadGameDataFile(gameFile string) error {
-	// Read game file
-	fileContentBytes, err := ioutil.ReadFile(gameFile)
+func (game *gameEngine) loadGameDataFile(gameFile string) error {
+	// Read game file, which may be compressed or archived
+	fileContentBytes, memberName, err := game.readGameFile(gameFile)
 	if err != nil {
 		return err
 	}
 
-	// Convert bytes to string
-	fileContent := string(fileContentBytes)
+	game.gameFileChecksum = crc32.ChecksumIEEE(fileContentBytes)
+	game.resetGameData()
 
+	if isTextGameData(fileContentBytes) {
+		var gameText string
+		if gameText, err = decodeGameText(fileContentBytes); err == nil {
+			err = game.loadTextGameData(gameText)
+		}
+	} else {
+		err = errors.New("unknown game file format")
+	}
+	if err == nil {
+		err = game.selectDialect()
+	}
+	if err != nil && memberName != gameFile {
+		return fmt.Errorf("archive member %s: %v", memberName, err)
+	}
+	return err
+}
+
+// Text game data starts with the number of bytes in the game
+func isTextGameData(data []byte) bool {
+	trimmed := bytes.TrimLeft(data, " \t\r\n")
+	return len(trimmed) > 0 && (trimmed[0] == '-' || (trimmed[0] >= '0' && trimmed[0] <= '9'))
+}
+
+// Forget any previously loaded game data
+func (game *gameEngine) resetGameData() {
+	game.actionData = nil
+	game.actionDescription = nil
+	game.listOfVerbsAndNouns = nil
+	game.message = nil
+	game.objectDescription = nil
+	game.objectLocation = nil
+	game.objectOriginalLocation = nil
+	game.roomDescription = nil
+	game.roomExit = nil
+	game.adventureVersion = 0
+	game.adventureNumber = 0
+}
+
+func (game *gameEngine) loadTextGameData(fileContent string) error {
 	// Replace newline with current system newline
 	fileContent = normalizeNewline(fileContent)
 
@@ -828,25 +1075,25 @@ func loadGameDataFile(gameFile string) error {
 	textPattern := regexp.MustCompile(`\s*"([^"]*)"([\s\S]*)`)
 
 	next := fileContent
-	gameBytes, next = extractInt(next)
-	numberOfObjects, next = extractInt(next)
-	numberOfActions, next = extractInt(next)
-	numberOfWords, next = extractInt(next)
-	numberOfRooms, next = extractInt(next)
-	maxObjectsCarried, next = extractInt(next)
-	if maxObjectsCarried < 0 {
-		maxObjectsCarried = REALLY_BIG_NUMBER
-	}
-	startingRoom, next = extractInt(next)
-	numberOfTreasures, next = extractInt(next)
-	wordLength, next = extractInt(next)
-	timeLimit, next = extractInt(next)
-	numberOfMessages, next = extractInt(next)
-	treasureRoomId, next = extractInt(next)
+	game.gameBytes, next = extractInt(next)
+	game.numberOfObjects, next = extractInt(next)
+	game.numberOfActions, next = extractInt(next)
+	game.numberOfWords, next = extractInt(next)
+	game.numberOfRooms, next = extractInt(next)
+	game.maxObjectsCarried, next = extractInt(next)
+	if game.maxObjectsCarried < 0 {
+		game.maxObjectsCarried = REALLY_BIG_NUMBER
+	}
+	game.startingRoom, next = extractInt(next)
+	game.numberOfTreasures, next = extractInt(next)
+	game.wordLength, next = extractInt(next)
+	game.timeLimit, next = extractInt(next)
+	game.numberOfMessages, next = extractInt(next)
+	game.treasureRoomId, next = extractInt(next)
 
 	// Extract actions
 	actionId := 0
-	for actionId <= numberOfActions {
+	for actionId <= game.numberOfActions {
 		actionIdEntry := 0
 		// Iterate over the 8 number values that make up an encoded action
 		var entryInAction []int
@@ -856,23 +1103,23 @@ func loadGameDataFile(gameFile string) error {
 			entryInAction = append(entryInAction, actionEntryValue)
 			actionIdEntry++
 		}
-		actionData = append(actionData, entryInAction)
+		game.actionData = append(game.actionData, entryInAction)
 		actionId++
 	}
 
 	// Extract words
-	listOfVerbsAndNouns = make([][]string, numberOfWords+1)
+	game.listOfVerbsAndNouns = make([][]string, game.numberOfWords+1)
 	word := 0
-	for word < ((numberOfWords + 1) * 2) {
+	for word < ((game.numberOfWords + 1) * 2) {
 		var input string
 		input, next = extractString(next, wordPattern)
-		listOfVerbsAndNouns[word/2] = append(listOfVerbsAndNouns[word/2], input)
+		game.listOfVerbsAndNouns[word/2] = append(game.listOfVerbsAndNouns[word/2], input)
 		word++
 	}
 
 	// Extract rooms
 	room := 0
-	for room <= numberOfRooms {
+	for room <= game.numberOfRooms {
 		matches := roomPattern.FindStringSubmatch(next)
 		if len(matches) > 0 {
 			var exit []int
@@ -880,8 +1127,8 @@ func loadGameDataFile(gameFile string) error {
 				exitNumber, _ := strconv.Atoi(matches[i+1])
 				exit = append(exit, exitNumber)
 			}
-			roomExit = append(roomExit, exit)
-			roomDescription = append(roomDescription, matches[7])
+			game.roomExit = append(game.roomExit, exit)
+			game.roomDescription = append(game.roomDescription, matches[7])
 			next = matches[8]
 		}
 		room++
@@ -889,22 +1136,22 @@ func loadGameDataFile(gameFile string) error {
 
 	// Extract messages
 	currentMessage := 0
-	for currentMessage <= numberOfMessages {
+	for currentMessage <= game.numberOfMessages {
 		var messageText string
 		messageText, next = extractString(next, textPattern)
-		message = append(message, messageText)
+		game.message = append(game.message, messageText)
 		currentMessage++
 	}
 
 	// Extract objects
 	object := 0
-	for object <= numberOfObjects {
+	for object <= game.numberOfObjects {
 		matches := objectPattern.FindStringSubmatch(next)
 		if len(matches) > 0 {
-			objectDescription = append(objectDescription, matches[1])
+			game.objectDescription = append(game.objectDescription, matches[1])
 			location, _ := strconv.Atoi(matches[2])
-			objectLocation = append(objectLocation, location)
-			objectOriginalLocation = append(objectOriginalLocation, location)
+			game.objectLocation = append(game.objectLocation, location)
+			game.objectOriginalLocation = append(game.objectOriginalLocation, location)
 			next = matches[3]
 
 		}
@@ -913,22 +1160,41 @@ func loadGameDataFile(gameFile string) error {
 
 	// Extract action descriptions
 	actionCounter := 0
-	for actionCounter <= numberOfActions {
+	for actionCounter <= game.numberOfActions {
 		var descriptionText string
 		descriptionText, next = extractString(next, textPattern)
-		actionDescription = append(actionDescription, descriptionText)
+		game.actionDescription = append(game.actionDescription, descriptionText)
 		actionCounter++
 	}
 
 	// Extract adventure version and number
-	adventureVersion, next = extractInt(next)
-	adventureNumber, next = extractInt(next)
+	game.adventureVersion, next = extractInt(next)
+	game.adventureNumber, next = extractInt(next)
 
 	// Replace Ascii 96 with Ascii 34 in output text strings
-	replaceInStringSlice(objectDescription, "`", `"`)
-	replaceInStringSlice(message, "`", `"`)
-	replaceInStringSlice(roomDescription, "`", `"`)
+	replaceInStringSlice(game.objectDescription, "`", `"`)
+	replaceInStringSlice(game.message, "`", `"`)
+	replaceInStringSlice(game.roomDescription, "`", `"`)
 
+	return game.checkGameData()
+}
+
+// Make sure that the loaded data is complete enough to be played
+func (game *gameEngine) checkGameData() error {
+	switch {
+	case len(game.actionData) != game.numberOfActions+1 || len(game.actionDescription) != game.numberOfActions+1:
+		return errors.New("incomplete action data")
+	case len(game.listOfVerbsAndNouns) <= VERB_DROP:
+		return errors.New("incomplete vocabulary")
+	case len(game.roomDescription) != game.numberOfRooms+1 || len(game.roomExit) != game.numberOfRooms+1:
+		return errors.New("incomplete room data")
+	case len(game.message) != game.numberOfMessages+1:
+		return errors.New("incomplete message data")
+	case len(game.objectDescription) != game.numberOfObjects+1 || game.numberOfObjects < LIGHT_SOURCE_ID:
+		return errors.New("incomplete object data")
+	case game.startingRoom < 0 || game.startingRoom > game.numberOfRooms:
+		return errors.New("starting room out of range")
+	}
 	return nil
 }
 
@@ -960,183 +1226,179 @@ func normalizeNewline(s string) string {
 	return strings.Replace(s, "\r\n", "\n", -1)
 }
 
-func cls() bool {
-	fmt.Print("\033[H\033[2J")
+func (game *gameEngine) cls() bool {
+	game.output.clearWindow(WINDOW_MAIN)
 	return true
 }
 
-func extractWords() int {
-	//Reset extractedInputWords slice
-	extractedInputWords = []string{}
-
-	// Trim leading white spaces
-	keyboardInput2 = strings.TrimLeft(keyboardInput2, " ")
-
+func (game *gameEngine) extractWords() int {
 	//Split keyboardInput2 into words
-	extractedInputWords = strings.Split(keyboardInput2, " ")
+	game.extractedInputWords = tokenizeInput(game.keyboardInput2)
 
-	if len(extractedInputWords) == 0 {
-		extractedInputWords = append(extractedInputWords, "")
+	if len(game.extractedInputWords) == 0 {
+		game.extractedInputWords = append(game.extractedInputWords, "")
 	}
 
-	resolveGoShortcut()
+	game.resolveGoShortcut()
 
 	// If the length of extractedInputWords is less than 2, add an empty string
-	if len(extractedInputWords) < 2 {
-		extractedInputWords = append(extractedInputWords, "")
+	if len(game.extractedInputWords) < 2 {
+		game.extractedInputWords = append(game.extractedInputWords, "")
 	}
-	globalNoun = extractedInputWords[1]
+	game.separateAdjectives()
+	game.resolvePronoun()
+	game.globalNoun = game.extractedInputWords[1]
 
 	//Reset foundWord slice
-	foundWord = []int{0, 0}
+	game.foundWord = []int{0, 0}
 
 	for verbOrNoun := 0; verbOrNoun <= 1; verbOrNoun++ {
 		nonSynonym := 0
-		for wordId, word := range listOfVerbsAndNouns {
+		for wordId, word := range game.listOfVerbsAndNouns {
 			if strings.Index(word[verbOrNoun], "*") != 0 {
 				nonSynonym = wordId
 			}
-			tempWord := strings.TrimLeft(word[verbOrNoun], "*")
-			tempWord = extractFirstCharacters(tempWord, wordLength)
-			if tempWord == strings.ToUpper(extractFirstCharacters(extractedInputWords[verbOrNoun], wordLength)) {
-				foundWord[verbOrNoun] = nonSynonym
+			if game.sameWord(strings.TrimLeft(word[verbOrNoun], "*"), game.extractedInputWords[verbOrNoun]) {
+				game.foundWord[verbOrNoun] = nonSynonym
 				break
 			}
 		}
 	}
+	if fuzzyMatching {
+		game.correctTypos()
+	}
 	return 1
 }
 
 func extractFirstCharacters(input string, limit int) string {
-	if len(input) >= limit {
-		return input[:limit]
+	characters := []rune(input)
+	if len(characters) >= limit {
+		return string(characters[:limit])
 	}
 	return input
 }
 
-func boolSliceToIntSlice(boolSlice []bool) []int {
-	intSlice := make([]int, len(boolSlice))
-	for i, val := range boolSlice {
-		if val {
-			intSlice[i] = 1
-		} else {
-			intSlice[i] = 0
-		}
+// Copy the current game state into a snapshot
+func (game *gameEngine) takeSnapshot() gameSnapshot {
+	return gameSnapshot{
+		currentRoom:      game.currentRoom,
+		alternateRoom:    append([]int(nil), game.alternateRoom...),
+		counterRegister:  game.counterRegister,
+		alternateCounter: append([]int(nil), game.alternateCounter...),
+		objectLocation:   append([]int(nil), game.objectLocation...),
+		statusFlag:       append([]bool(nil), game.statusFlag...),
+		prngState:        game.prngState,
+		turnCounter:      game.turnCounter,
 	}
-	return intSlice
 }
 
-func saveGame() bool {
-	fmt.Println("Name of save file:")
-	reader := bufio.NewReader(os.Stdin)
-	saveFileName, _ := reader.ReadString('\n')
-	saveFileName = strings.TrimSuffix(saveFileName, "\n")
-
-	saveData := []int{adventureVersion, adventureNumber, currentRoom}
-	saveData = append(saveData, alternateRoom...)
-	saveData = append(saveData, counterRegister)
-	saveData = append(saveData, alternateCounter...)
-	saveData = append(saveData, objectLocation...)
-	statusFlagInt := boolSliceToIntSlice(statusFlag)
-	saveData = append(saveData, statusFlagInt...)
-
-	saveFile, _ := os.Create(saveFileName)
-	for _, data := range saveData {
-		fmt.Fprintln(saveFile, data)
-	}
-	saveFile.Close()
-
-	return true
+// Replace the current game state with the contents of a snapshot
+func (game *gameEngine) restoreSnapshot(snapshot gameSnapshot) {
+	game.currentRoom = snapshot.currentRoom
+	copy(game.alternateRoom, snapshot.alternateRoom)
+	game.counterRegister = snapshot.counterRegister
+	copy(game.alternateCounter, snapshot.alternateCounter)
+	copy(game.objectLocation, snapshot.objectLocation)
+	copy(game.statusFlag, snapshot.statusFlag)
+	game.prngState = snapshot.prngState
+	game.turnCounter = snapshot.turnCounter
 }
 
-func intToBool(num int) bool {
-	if num == FALSE_VALUE {
-		return false
-	} else {
-		return true
+func (game *gameEngine) pushUndoSnapshot(snapshot gameSnapshot) {
+	if undoDepth < 1 {
+		return
+	}
+	game.undoHistory = append(game.undoHistory, snapshot)
+	if len(game.undoHistory) > undoDepth {
+		game.undoHistory = game.undoHistory[len(game.undoHistory)-undoDepth:]
 	}
 }
 
-func loadGame() bool {
-	fmt.Println("Name of save file:")
-	reader := bufio.NewReader(os.Stdin)
-	saveFileName, _ := reader.ReadString('\n')
-	saveFileName = strings.TrimSuffix(saveFileName, "\n")
-
-	saveFile, err := os.Open(saveFileName)
-	if err != nil {
-		fmt.Printf("Couldn't load \"%s\". Doesn't exist!\n", saveFileName)
+func (game *gameEngine) undoTurn() bool {
+	if len(game.undoHistory) == 0 {
+		fmt.Fprintln(game.output, localize("Nothing to undo"))
 		return false
 	}
+	game.restoreSnapshot(game.undoHistory[len(game.undoHistory)-1])
+	game.undoHistory = game.undoHistory[:len(game.undoHistory)-1]
+	fmt.Fprintln(game.output, localize("Previous turn undone"))
+	return true
+}
 
-	var saveData []int
-	scanner := bufio.NewScanner(saveFile)
-	for scanner.Scan() {
-		var num int
-		fmt.Sscan(scanner.Text(), &num)
-		saveData = append(saveData, num)
+func ramSaveSlotName(name string) string {
+	if name == "" {
+		return "DEFAULT"
 	}
+	return strings.ToUpper(name)
+}
 
-	saveAdventureVersion := saveData[0]
-	if saveAdventureVersion != adventureVersion {
-		fmt.Println("Invalid savegame version")
+// Keep a snapshot of the game in memory, without involving any files
+func (game *gameEngine) saveRam(name string) bool {
+	name = ramSaveSlotName(name)
+	if _, exists := game.ramSaveSlot[name]; !exists && len(game.ramSaveSlot) >= RAM_SAVE_SLOTS {
+		fmt.Fprintf(game.output, localize("All %d quick save slots are in use. Slots: %s\n"), RAM_SAVE_SLOTS, game.listRamSaveSlots())
 		return false
 	}
+	game.ramSaveSlot[name] = game.takeSnapshot()
+	fmt.Fprintf(game.output, localize("Game saved to memory slot %s\n"), name)
+	return true
+}
 
-	saveAdventureNumber := saveData[1]
-	if saveAdventureNumber != adventureNumber {
-		fmt.Println("Invalid savegame adventure number")
+func (game *gameEngine) restoreRam(name string) bool {
+	name = ramSaveSlotName(name)
+	snapshot, exists := game.ramSaveSlot[name]
+	if !exists {
+		if len(game.ramSaveSlot) == 0 {
+			fmt.Fprintln(game.output, localize("Nothing has been saved to memory"))
+		} else {
+			fmt.Fprintf(game.output, localize("No memory slot called %s. Slots: %s\n"), name, game.listRamSaveSlots())
+		}
 		return false
 	}
+	game.restoreSnapshot(snapshot)
+	fmt.Fprintf(game.output, localize("Game restored from memory slot %s\n"), name)
+	return true
+}
 
-	currentRoom = saveData[2]
-	for i := range alternateRoom {
-		alternateRoom[i] = saveData[i+3]
+func (game *gameEngine) listRamSaveSlots() string {
+	var names []string
+	for name := range game.ramSaveSlot {
+		names = append(names, name)
 	}
-	counterRegister = saveData[3+len(alternateRoom)]
-	for i := range alternateCounter {
-		alternateCounter[i] = saveData[i+4+len(alternateRoom)]
-	}
-	for i := range objectLocation {
-		objectLocation[i] = saveData[i+4+len(alternateRoom)+len(alternateCounter)]
-	}
-	for i := range statusFlag {
-		statusFlag[i] = intToBool(saveData[i+4+len(alternateRoom)+len(alternateCounter)+len(objectLocation)])
-	}
-
-	return true
+	sort.Strings(names)
+	return strings.Join(names, " ")
 }
 
-func runActions(inputVerb int, inputNoun int) bool {
+func (game *gameEngine) runActions(inputVerb int, inputNoun int) bool {
 	if inputVerb == VERB_GO && inputNoun <= DIRECTION_NOUNS {
-		handleGoVerb()
+		game.handleGoVerb()
 		return true
 	}
 
 	foundWord := false
 
-	contFlag = false
+	game.contFlag = false
 	wordActionDone := false
-	for currentAction, _ := range actionDescription {
-		actionVerb := getActionVerb(currentAction)
-		actionNoun := getActionNoun(currentAction)
+	for currentAction, _ := range game.actionDescription {
+		actionVerb := game.getActionVerb(currentAction)
+		actionNoun := game.getActionNoun(currentAction)
 
 		// CONT action
-		if contFlag && actionVerb == 0 && actionNoun == 0 {
-			if evaluateConditions(currentAction) {
-				executeCommands(currentAction)
+		if game.contFlag && actionVerb == 0 && actionNoun == 0 {
+			if game.evaluateConditions(currentAction) {
+				game.executeCommands(currentAction)
 			}
 		} else {
-			contFlag = false
+			game.contFlag = false
 		}
 
 		// AUT action
 		if inputVerb == 0 {
 			if actionVerb == 0 && actionNoun > 0 {
-				contFlag = false
-				if getPrn() < actionNoun {
-					if evaluateConditions(currentAction) {
-						executeCommands(currentAction)
+				game.contFlag = false
+				if game.getPrn() < actionNoun {
+					if game.evaluateConditions(currentAction) {
+						game.executeCommands(currentAction)
 					}
 				}
 			}
@@ -1146,22 +1408,22 @@ func runActions(inputVerb int, inputNoun int) bool {
 		if inputVerb > 0 {
 			if actionVerb == inputVerb {
 				if wordActionDone == false {
-					contFlag = false
+					game.contFlag = false
 					if actionNoun == 0 {
 						foundWord = true
-						if evaluateConditions(currentAction) {
-							executeCommands(currentAction)
+						if game.evaluateConditions(currentAction) {
+							game.executeCommands(currentAction)
 							wordActionDone = true
-							if contFlag == false {
+							if game.contFlag == false {
 								return true
 							}
 						}
 					} else if actionNoun == inputNoun {
 						foundWord = true
-						if evaluateConditions(currentAction) {
-							executeCommands(currentAction)
+						if game.evaluateConditions(currentAction) {
+							game.executeCommands(currentAction)
 							wordActionDone = true
-							if contFlag == false {
+							if game.contFlag == false {
 								return true
 							}
 						}
@@ -1176,7 +1438,7 @@ func runActions(inputVerb int, inputNoun int) bool {
 	}
 
 	if wordActionDone == false {
-		if handleCarryAndDropVerb(inputVerb, inputNoun) {
+		if game.handleCarryAndDropVerb(inputVerb, inputNoun) {
 			return true
 		}
 	}
@@ -1186,20 +1448,20 @@ func runActions(inputVerb int, inputNoun int) bool {
 	}
 
 	if foundWord {
-		fmt.Println("I can't do that yet")
+		fmt.Fprintln(game.output, localize("I can't do that yet"))
 	} else {
-		fmt.Println("I don't understand your command")
+		fmt.Fprintln(game.output, localize("I don't understand your command"))
 	}
 
 	return true
 }
 
-func nounIsInObject() bool {
-	truncatedNoun := globalNoun[:wordLength]
-	for _, description := range objectDescription {
+func (game *gameEngine) nounIsInObject() bool {
+	truncatedNoun := extractFirstCharacters(game.globalNoun, game.wordLength)
+	for _, description := range game.objectDescription {
 		if strings.Contains(description, "/") {
-			objectNoun := strings.ToLower(strings.Split(description, "/")[1])
-			if objectNoun == truncatedNoun {
+			objectNoun := strings.Split(description, "/")[1]
+			if strings.EqualFold(objectNoun, truncatedNoun) {
 				return true
 			}
 		}
@@ -1207,15 +1469,15 @@ func nounIsInObject() bool {
 	return false
 }
 
-func handleCarryAndDropVerb(inputVerb, inputNoun int) bool {
+func (game *gameEngine) handleCarryAndDropVerb(inputVerb, inputNoun int) bool {
 	// Exit function if the verb isn't carry or drop
 	if inputVerb != VERB_CARRY && inputVerb != VERB_DROP {
 		return false
 	}
 
 	// If noun is undefined, return with an error text
-	if inputNoun == 0 && !nounIsInObject() {
-		fmt.Println("What?")
+	if inputNoun == 0 && !game.nounIsInObject() {
+		fmt.Fprintln(game.output, localize("What?"))
 		return true
 	}
 
@@ -1223,30 +1485,30 @@ func handleCarryAndDropVerb(inputVerb, inputNoun int) bool {
 	if inputVerb == VERB_CARRY {
 		carriedObjects := 0
 
-		for _, location := range objectLocation {
+		for _, location := range game.objectLocation {
 			if location == ROOM_INVENTORY {
 				carriedObjects++
 			}
 		}
 
-		if carriedObjects >= maxObjectsCarried {
-			if maxObjectsCarried >= 0 {
-				fmt.Println("I've too much too carry. try -take inventory-")
+		if carriedObjects >= game.maxObjectsCarried {
+			if game.maxObjectsCarried >= 0 {
+				fmt.Fprintln(game.output, localize("I've too much too carry. try -take inventory-"))
 				return true
 			}
 		} else {
-			if getOrDropNoun(inputNoun, currentRoom, ROOM_INVENTORY) {
+			if game.getOrDropNoun(inputNoun, game.currentRoom, ROOM_INVENTORY) {
 				return true
 			} else {
-				fmt.Println("I don't see it here")
+				fmt.Fprintln(game.output, localize("I don't see it here"))
 				return true
 			}
 		}
 	} else {
-		if getOrDropNoun(inputNoun, ROOM_INVENTORY, currentRoom) {
+		if game.getOrDropNoun(inputNoun, ROOM_INVENTORY, game.currentRoom) {
 			return true
 		} else {
-			fmt.Println("I'm not carrying it")
+			fmt.Fprintln(game.output, localize("I'm not carrying it"))
 			return true
 		}
 	}
@@ -1254,79 +1516,108 @@ func handleCarryAndDropVerb(inputVerb, inputNoun int) bool {
 	return false
 }
 
-func getOrDropNoun(inputNoun, roomSource, roomDestination int) bool {
+func (game *gameEngine) getOrDropNoun(inputNoun, roomSource, roomDestination int) bool {
 	var objectsInRoom []int
 	objectCounter := 0
 
 	// Identify all objects in current room
-	for _, location := range objectLocation {
+	for _, location := range game.objectLocation {
 		if location == roomSource {
 			objectsInRoom = append(objectsInRoom, objectCounter)
 		}
 		objectCounter++
 	}
 
-	// Check if any of the objects in the room has a matching noun
+	// Find the objects in the room that have a matching noun
+	var candidates []int
 	for _, roomObject := range objectsInRoom {
 
 		// Only proceed if the object has a noun defined
-		if strings.Contains(objectDescription[roomObject], "/") {
-
-			// Pick up the first object we find that matches and return
-			noun := strings.Split(objectDescription[roomObject], "/")[1]
-			if listOfVerbsAndNouns[inputNoun][1] == noun || noun == strings.ToUpper(globalNoun[:wordLength]) {
-				objectLocation[roomObject] = roomDestination
-				fmt.Println("OK")
-				return true
+		if strings.Contains(game.objectDescription[roomObject], "/") {
+			noun := strings.Split(game.objectDescription[roomObject], "/")[1]
+			if game.listOfVerbsAndNouns[inputNoun][1] == noun || game.sameWord(noun, game.globalNoun) {
+				candidates = append(candidates, roomObject)
 			}
 		}
 	}
-	return false
+	if len(candidates) == 0 {
+		return false
+	}
+
+	// Describing words in the command narrow it down, and if there's still
+	// more than one object to choose from, the player is asked
+	if len(game.inputAdjectives) > 0 {
+		if described := game.objectsMatchingWords(candidates, game.inputAdjectives); len(described) > 0 {
+			candidates = described
+		}
+	}
+	chosenObject := candidates[0]
+	if len(candidates) > 1 {
+		var chosen bool
+		if chosenObject, chosen = game.chooseObject(candidates); !chosen {
+			fmt.Fprintln(game.output, localize("What?"))
+			return true
+		}
+		// In a shared world, someone else may have taken it while the player
+		// was asked
+		if game.objectLocation[chosenObject] != roomSource {
+			return false
+		}
+	}
+	game.objectLocation[chosenObject] = roomDestination
+	fmt.Fprintln(game.output, localize("OK"))
+	return true
 }
 
-func getActionVerb(actionId int) int {
-	return actionData[actionId][0] / COMMAND_CODE_DIVISOR
+func (game *gameEngine) getActionVerb(actionId int) int {
+	return game.actionData[actionId][0] / COMMAND_CODE_DIVISOR
 }
 
-func getActionNoun(actionId int) int {
-	return actionData[actionId][0] % COMMAND_CODE_DIVISOR
+func (game *gameEngine) getActionNoun(actionId int) int {
+	return game.actionData[actionId][0] % COMMAND_CODE_DIVISOR
 }
 
-func executeCommands(actionId int) int {
-	commandParameterIndex = 1
+func (game *gameEngine) executeCommands(actionId int) int {
+	game.commandParameterIndex = 1
 	command := 0
 	continueExecutingCommands := true
 
 	for command < COMMANDS_IN_ACTION && continueExecutingCommands {
-		commandOrDisplayMessage := decodeCommandFromData(command, actionId)
+		commandOrDisplayMessage := game.decodeCommandFromData(command, actionId)
 		command++
 
 		// Code above 102? it's printable text!
 		if commandOrDisplayMessage >= MESSAGE_2_START {
-			fmt.Println(message[commandOrDisplayMessage-MESSAGE_1_END+1])
+			fmt.Fprintln(game.output, game.message[commandOrDisplayMessage-MESSAGE_1_END+1])
 		} else if commandOrDisplayMessage == 0 {
 			// Do nothing
 		} else if commandOrDisplayMessage <= MESSAGE_1_END {
 			// Code below 52? it's printable text!
-			fmt.Println(message[commandOrDisplayMessage])
+			fmt.Fprintln(game.output, game.message[commandOrDisplayMessage])
 		} else {
 			// Code above 52 and below 102? We got some command code to run!
 			commandCode := commandOrDisplayMessage - MESSAGE_1_END - 1
+			if commandCode >= game.dialectCommands() {
+				if flagDebug {
+					fmt.Fprintf(game.output, "Unknown command %d in action %d\n", commandCode, actionId)
+				}
+				continue
+			}
 			// Launch execution of action commands
-			commandFunction[commandCode](&actionId, &continueExecutingCommands)
+			commandFunction[commandCode](game, &actionId, &continueExecutingCommands)
 		}
 	}
 
 	return 1
 }
 
-func evaluateConditions(actionId int) bool {
+func (game *gameEngine) evaluateConditions(actionId int) bool {
 	evaluationStatus := true
 	condition := 1
 	for condition <= CONDITIONS {
-		conditionCode := getConditionCode(actionId, condition)
-		conditionParameter := getConditionParameter(actionId, condition)
-		condition_success := conditionFunction[conditionCode](conditionParameter)
+		conditionCode := game.getConditionCode(actionId, condition)
+		conditionParameter := game.getConditionParameter(actionId, condition)
+		condition_success := conditionFunction[conditionCode](game, conditionParameter)
 		if !condition_success {
 			// Stop evaluating conditions if false. One fails all.
 			evaluationStatus = false
@@ -1337,21 +1628,21 @@ func evaluateConditions(actionId int) bool {
 	return evaluationStatus
 }
 
-func getConditionCode(actionId int, condition int) int {
-	conditionRaw := actionData[actionId][condition]
+func (game *gameEngine) getConditionCode(actionId int, condition int) int {
+	conditionRaw := game.actionData[actionId][condition]
 	conditionCode := conditionRaw % CONDITION_DIVISOR
 	return conditionCode
 }
 
-func getConditionParameter(actionId int, condition int) int {
-	conditionRaw := actionData[actionId][condition]
+func (game *gameEngine) getConditionParameter(actionId int, condition int) int {
+	conditionRaw := game.actionData[actionId][condition]
 	conditionParameter := conditionRaw / CONDITION_DIVISOR
 	return conditionParameter
 }
 
-func resolveGoShortcut() int {
-	enteredInputVerb := strings.ToLower(extractedInputWords[0])
-	viablePhrases := getViableWordActions()
+func (game *gameEngine) resolveGoShortcut() int {
+	enteredInputVerb := strings.ToLower(game.extractedInputWords[0])
+	viablePhrases := game.getViableWordActions()
 
 	// Don't attempt to resolve go shortcuts if input is empty
 	if len(enteredInputVerb) < 1 {
@@ -1360,40 +1651,34 @@ func resolveGoShortcut() int {
 
 	// Don't make shortcut if input verb matches legitimate word action
 	for viableVerb := range viablePhrases {
-		possibleVerbText := strings.ToLower(listOfVerbsAndNouns[viableVerb][0])
-		shortenedVerb := enteredInputVerb
-		if len(possibleVerbText) < len(enteredInputVerb) {
-			shortenedVerb = enteredInputVerb[0:len(possibleVerbText)]
-		}
+		possibleVerbText := strings.ToLower(game.listOfVerbsAndNouns[viableVerb][0])
+		shortenedVerb := extractFirstCharacters(enteredInputVerb, len([]rune(possibleVerbText)))
 		if shortenedVerb == possibleVerbText {
 			return 1
 		}
 	}
 
 	for direction := 1; direction <= DIRECTION_NOUNS; direction++ {
-		directionNounText := strings.ToLower(listOfVerbsAndNouns[direction][1])
-		shortenedDirection := directionNounText
-		if len(enteredInputVerb) < len(directionNounText) {
-			shortenedDirection = directionNounText[0:len(enteredInputVerb)]
-		}
+		directionNounText := strings.ToLower(game.listOfVerbsAndNouns[direction][1])
+		shortenedDirection := extractFirstCharacters(directionNounText, len([]rune(enteredInputVerb)))
 
 		if enteredInputVerb == shortenedDirection {
-			extractedInputWords[0] = strings.ToLower(listOfVerbsAndNouns[VERB_GO][0])
-			extractedInputWords = append(extractedInputWords, directionNounText)
+			game.extractedInputWords[0] = strings.ToLower(game.listOfVerbsAndNouns[VERB_GO][0])
+			game.extractedInputWords = append(game.extractedInputWords, directionNounText)
 			return 1
 		}
 	}
 	return 1
 }
 
-func getViableWordActions() map[int]map[int]string {
+func (game *gameEngine) getViableWordActions() map[int]map[int]string {
 	viablePhrases := make(map[int]map[int]string)
 	currentAction := 0
-	for range actionData {
-		actionVerb := getActionVerb(currentAction)
-		actionNoun := getActionNoun(currentAction)
+	for range game.actionData {
+		actionVerb := game.getActionVerb(currentAction)
+		actionNoun := game.getActionNoun(currentAction)
 		if actionVerb > 0 {
-			if evaluateConditions(currentAction) {
+			if game.evaluateConditions(currentAction) {
 				if _, ok := viablePhrases[actionVerb]; !ok {
 					viablePhrases[actionVerb] = make(map[int]string)
 				}
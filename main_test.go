@@ -0,0 +1,40 @@
+package main
+
+import "testing"
+
+// Loading a saved game and restoring a memory slot can be undone like any
+// other turn, back to where the game was before
+func TestUndoAcrossRestore(t *testing.T) {
+	game := loadFixture(t, "adventure.dat")
+	game.saveRoot = t.TempDir()
+	game.initializeGameState()
+	clearing := game.currentRoom
+	game.playCommands("GET LAMP\nSAVE GAME\nslot1\nS\n")
+	cave := game.currentRoom
+	if cave == clearing {
+		t.Fatal("going south didn't leave the forest clearing")
+	}
+
+	tests := []struct {
+		name     string
+		commands string
+		room     int
+		carried  bool
+	}{
+		{"LOAD GAME", "LOAD GAME\nslot1\n", clearing, true},
+		{"UNDO after LOAD GAME", "UNDO\n", cave, true},
+		{"RESTORE RAM", "SAVE RAM\nDROP LAMP\nN\nRESTORE RAM\n", cave, true},
+		{"UNDO after RESTORE RAM", "UNDO\n", clearing, false},
+		{"UNDO before RESTORE RAM", "UNDO\n", cave, false},
+		{"UNDO again", "UNDO\n", cave, true},
+	}
+	for _, test := range tests {
+		game.playCommands(test.commands)
+		if game.currentRoom != test.room {
+			t.Errorf("%s: in room %d, wanted %d", test.name, game.currentRoom, test.room)
+		}
+		if carried := game.objectLocation[LIGHT_SOURCE_ID] == ROOM_INVENTORY; carried != test.carried {
+			t.Errorf("%s: carrying the lamp is %v, wanted %v", test.name, carried, test.carried)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// The messages of the interpreter itself are written in English in the code,
+// and looked up in a catalog for other languages. Anything missing from a
+// catalog is shown in English.
+
+const DEFAULT_LANGUAGE string = "en"
+
+var (
+	language         string = DEFAULT_LANGUAGE
+	selectedLanguage string
+)
+
+var messageCatalog = map[string]map[string]string{
+	"en": {},
+	"de": germanMessages,
+}
+
+var germanMessages = map[string]string{
+	// Answers
+	"Y": "J",
+
+	// The game
+	"Tell me what to do":                            "Was soll ich tun?",
+	"You use word(s) I don't know":                  "Du benutzt Wörter, die ich nicht kenne",
+	"I don't understand your command":               "Ich verstehe deinen Befehl nicht",
+	"I can't do that yet":                           "Das kann ich noch nicht tun",
+	"I've too much too carry. try -take inventory-": "Ich trage schon zu viel. Versuche -Inventar-",
+	"I'm dead...":                                   "Ich bin tot...",
+	"Well done.":                                    "Gut gemacht.",
+	"Light has run out":                             "Das Licht ist ausgegangen",
+	"Light runs out in %d turns!\n":                 "Das Licht geht in %d Zügen aus!\n",
+	"Dangerous to move in the dark!":                "Es ist gefährlich, sich im Dunkeln zu bewegen!",
+	"Give me a direction too.":                      "Sag mir auch eine Richtung.",
+	"I fell down and broke my neck.":                "Ich bin gestürzt und habe mir das Genick gebrochen.",
+	"I can't go in that direction":                  "In diese Richtung kann ich nicht gehen",
+	"What?":                                         "Was?",
+	"OK":                                            "OK",
+	"I don't see it here":                           "Ich sehe das hier nicht",
+	"I'm not carrying it":                           "Ich trage das nicht",
+	"Nothing":                                       "Nichts",
+	"Press Enter to continue":                       "Weiter mit der Eingabetaste",
+	"I can't see: Its too dark.":                    "Ich kann nichts sehen: Es ist zu dunkel.",
+	"I'm in a %s":                                   "Ich bin hier: %s",
+	". Visible items here: \n":                      ". Ich sehe hier: \n",
+	"Obvious exits: %s":                             "Ausgänge: %s",
+	"NORTH":                                         "NORDEN",
+	"SOUTH":                                         "SÜDEN",
+	"EAST":                                          "OSTEN",
+	"WEST":                                          "WESTEN",
+	"UP":                                            "OBEN",
+	"DOWN":                                          "UNTEN",
+	"Which one? %s\n":                               "Welches? %s\n",
+	"Did you mean %s?\n":                            "Meintest du %s?\n",
+	"Did you mean %s or %s?\n":                      "Meintest du %s oder %s?\n",
+	" Turns: %d   Light: %d   Score: %d%%":          " Züge: %d   Licht: %d   Punkte: %d%%",
+	"I've stored %d treasures. ON A SCALE OF 0 TO %d THAT RATES A %d\n": "Ich habe %d Schätze gesammelt. AUF EINER SKALA VON 0 BIS %d IST DAS EINE %d\n",
+
+	// Interpreter commands
+	"Do you really want to restart the game? (Y/N)":   "Willst du das Spiel wirklich neu beginnen? (J/N)",
+	"Do you really want to quit? (Y/N)":               "Willst du das Spiel wirklich beenden? (J/N)",
+	"Nothing to undo":                                 "Es gibt nichts zurückzunehmen",
+	"Previous turn undone":                            "Der letzte Zug wurde zurückgenommen",
+	"All %d quick save slots are in use. Slots: %s\n": "Alle %d Speicherplätze im Speicher sind belegt. Plätze: %s\n",
+	"Game saved to memory slot %s\n":                  "Spiel im Speicherplatz %s gespeichert\n",
+	"Nothing has been saved to memory":                "Im Speicher wurde nichts gespeichert",
+	"No memory slot called %s. Slots: %s\n":           "Es gibt keinen Speicherplatz %s. Plätze: %s\n",
+	"Game restored from memory slot %s\n":             "Spiel aus Speicherplatz %s geladen\n",
+
+	// Saved games
+	"Name of save slot (Enter for \"%s\"):\n":                      "Name des Spielstands (Eingabetaste für \"%s\"):\n",
+	"Couldn't save game: %v\n":                                     "Das Spiel konnte nicht gespeichert werden: %v\n",
+	"\"%s\" already exists. Overwrite it? (Y/N)":                   "\"%s\" gibt es schon. Überschreiben? (J/N)",
+	"Game not saved":                                               "Das Spiel wurde nicht gespeichert",
+	"Game saved":                                                   "Spiel gespeichert",
+	"Couldn't load game: %v\n":                                     "Das Spiel konnte nicht geladen werden: %v\n",
+	"Couldn't load \"%s\". Doesn't exist!\n":                       "\"%s\" konnte nicht geladen werden. Gibt es nicht!\n",
+	"Couldn't list saved games: %v\n":                              "Die Spielstände konnten nicht aufgelistet werden: %v\n",
+	"No saved games":                                               "Keine Spielstände",
+	"Saved games in %s:\n":                                         "Spielstände in %s:\n",
+	"%-16s %-*s %5d turns  %s\n":                                   "%-16s %-*s %5d Züge  %s\n",
+	"Couldn't remove autosaved game: %v\n":                         "Der automatisch gespeicherte Spielstand konnte nicht gelöscht werden: %v\n",
+	"Couldn't autosave game: %v\n":                                 "Das Spiel konnte nicht automatisch gespeichert werden: %v\n",
+	"Game autosaved":                                               "Spiel automatisch gespeichert",
+	"An autosaved game was found (%s, %d turns). Resume it? (Y/N)": "Es gibt einen automatisch gespeicherten Spielstand (%s, %d Züge). Fortsetzen? (J/N)",
+
+	// Game files
+	"Couldn't load \"%s\": %v\n":                                       "\"%s\" konnte nicht geladen werden: %v\n",
+	"The next part of this adventure couldn't be found":                "Der nächste Teil dieses Abenteuers wurde nicht gefunden",
+	"Loading the next part of the adventure...":                        "Der nächste Teil des Abenteuers wird geladen...",
+	"The archive holds several games:":                                 "Das Archiv enthält mehrere Spiele:",
+	"Which one do you want to play? (1-%d)\n":                          "Welches willst du spielen? (1-%d)\n",
+	"Games in %s:\n\n":                                                 "Spiele in %s:\n\n",
+	"Which game do you want to play? (1-%d, Enter for 1, Q to quit)\n": "Welches Spiel willst du spielen? (1-%d, Eingabetaste für 1, Q zum Beenden)\n",
+	"Title":       "Titel",
+	"File":        "Datei",
+	"Adv":         "Abent.",
+	"Rooms":       "Räume",
+	"Items":       "Dinge",
+	"Treas.":      "Schätze",
+	"Saves":       "Stände",
+	"Last played": "Zuletzt gespielt",
+	"The split-window mode needs a terminal that understands control codes": "Die geteilte Ansicht braucht ein Terminal, das Steuerzeichen versteht",
+
+	// Telnet server
+	"\nDisconnected after %v without input\n":         "\nVerbindung nach %v ohne Eingabe getrennt\n",
+	"Too many players at the moment. Try again later": "Im Moment spielen zu viele. Versuche es später noch einmal",
+	"\nThe server is shutting down. Goodbye!\n":       "\nDer Server wird beendet. Auf Wiedersehen!\n",
+
+	// Shared worlds
+	"What's your name?":                    "Wie heißt du?",
+	"Someone":                              "Jemand",
+	"%s is here\n":                         "%s ist hier\n",
+	"%s arrives\n":                         "%s kommt herein\n",
+	"%s leaves\n":                          "%s geht hinaus\n",
+	"%s picks up the %s\n":                 "%s nimmt: %s\n",
+	"%s drops the %s\n":                    "%s legt ab: %s\n",
+	"%s has left the game\n":               "%s hat das Spiel verlassen\n",
+	"That can't be done in a shared world": "Das geht nicht in einer gemeinsamen Welt",
+
+	introMessage: `
+                 *** Willkommen ***
+
+ Wenn nicht anders gesagt, musst du *SCHÄTZE* finden
+und-sie-an-ihren-richtigen--Platz-bringen!
+
+Ich bin deine Marionette. Gib mir Befehle aus
+einem Verb und einem Hauptwort. Ein paar Beispiele...
+
+Um zu sehen, was du trägst, kannst du sagen: TAKE INVENTORY
+um in ein Loch zu gehen, kannst du sagen: GO HOLE
+um das Spiel zu speichern: SAVE GAME
+
+Manchmal brauchst du besondere Dinge, um etwas zu tun. Aber
+ich bin sicher, du bist ein guter Abenteurer und findest das heraus.
+
+     Viel Spaß beim Abenteuer... Weiter mit der Eingabetaste`,
+}
+
+// Use the language given on the command line, or the one of the locale
+func selectLanguage() error {
+	if selectedLanguage != "" {
+		if _, exists := messageCatalog[selectedLanguage]; !exists {
+			return fmt.Errorf("unknown language \"%s\"", selectedLanguage)
+		}
+		language = selectedLanguage
+		return nil
+	}
+	for _, variable := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		locale := os.Getenv(variable)
+		if locale == "" {
+			continue
+		}
+		// A locale looks like de_DE.UTF-8. Anything without a language in it
+		// leaves the messages in English
+		fields := strings.FieldsFunc(locale, func(character rune) bool {
+			return character == '_' || character == '.' || character == '@'
+		})
+		if len(fields) == 0 {
+			return nil
+		}
+		locale = strings.ToLower(fields[0])
+		if _, exists := messageCatalog[locale]; exists {
+			language = locale
+		}
+		return nil
+	}
+	return nil
+}
+
+// The message in the language chosen by the player
+func localize(message string) string {
+	if translation, exists := messageCatalog[language][message]; exists {
+		return translation
+	}
+	return message
+}
@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+func TestLanguageFromLocale(t *testing.T) {
+	tests := []struct {
+		locale   string
+		language string
+	}{
+		{"de_DE.UTF-8", "de"},
+		{"DE", "de"},
+		{"de@euro", "de"},
+		{"fr_FR.UTF-8", DEFAULT_LANGUAGE},
+		{"C", DEFAULT_LANGUAGE},
+		{"@", DEFAULT_LANGUAGE},
+		{".", DEFAULT_LANGUAGE},
+		{"_.@", DEFAULT_LANGUAGE},
+	}
+	defer func() { language = DEFAULT_LANGUAGE }()
+	for _, test := range tests {
+		t.Setenv("LC_ALL", "")
+		t.Setenv("LC_MESSAGES", "")
+		t.Setenv("LANG", test.locale)
+		language = DEFAULT_LANGUAGE
+		if err := selectLanguage(); err != nil {
+			t.Fatal(err)
+		}
+		if language != test.language {
+			t.Errorf("LANG=%s: got language %s, wanted %s", test.locale, language, test.language)
+		}
+	}
+}
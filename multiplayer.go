@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// In a shared world, players play one game together. The objects, flags and
+// counters are the world's, while each player has a room and an inventory of
+// their own. When a player's turn is played, the world is brought into the
+// engine as that player sees it: what they carry is in the inventory, and what
+// the others carry is somewhere no room can see. Other players in the same
+// room are told what a player does.
+//
+// Only one player's turn is played at a time. When a turn waits for the
+// player partway through, to ask something or to pause, what it changed so far
+// is put back into the world and the others play meanwhile. After the wait,
+// the world is brought in again as it is then, so the turn goes on in the
+// world everyone else sees. Whatever the turn chose before the wait is checked
+// again before it's used, such as an object picked from several that someone
+// else may have taken.
+
+const (
+	ROOM_CARRIED_BY_OTHERS int = -2
+	MAX_PLAYER_NAME_LENGTH int = 20
+)
+
+var (
+	sharedWorldMode bool
+	telnetWorld     *sharedWorld
+)
+
+type sharedWorld struct {
+	// The game as it was set up, for new players to start from
+	start *gameEngine
+
+	mutex            sync.Mutex // Held while a player's turn is played
+	objectLocation   []int
+	carriedBy        map[int]*worldPlayer
+	statusFlag       []bool
+	counterRegister  int
+	alternateCounter []int
+	prngState        int
+	players          map[*worldPlayer]bool
+}
+
+type worldPlayer struct {
+	name  string
+	world *sharedWorld
+	game  *gameEngine
+}
+
+// Set up a world for players to join
+func newSharedWorld(fileName string) (*sharedWorld, error) {
+	start, err := loadSessionGame(fileName, terminalDisplay{io.Discard}, "")
+	if err != nil {
+		return nil, err
+	}
+	start.run(start.startGame)
+	return &sharedWorld{
+		start:            start,
+		objectLocation:   append([]int(nil), start.objectLocation...),
+		carriedBy:        make(map[int]*worldPlayer),
+		statusFlag:       append([]bool(nil), start.statusFlag...),
+		counterRegister:  start.counterRegister,
+		alternateCounter: append([]int(nil), start.alternateCounter...),
+		prngState:        start.prngState,
+		players:          make(map[*worldPlayer]bool),
+	}, nil
+}
+
+// A player who plays with what's typed in the input
+func (world *sharedWorld) newPlayer(display glkDisplay, input io.Reader, saveRoot string) *worldPlayer {
+	game := *world.start
+	player := &worldPlayer{world: world, game: &game}
+	// The start is shared by every player, so anything the engine changes in
+	// place is copied
+	game.alternateRoom = append([]int(nil), world.start.alternateRoom...)
+	game.alternateCounter = append([]int(nil), world.start.alternateCounter...)
+	game.objectLocation = append([]int(nil), world.start.objectLocation...)
+	game.statusFlag = append([]bool(nil), world.start.statusFlag...)
+	game.undoHistory = nil
+	game.foundWord = append([]int(nil), world.start.foundWord...)
+	game.extractedInputWords = append([]string(nil), world.start.extractedInputWords...)
+	game.inputAdjectives = nil
+	game.wordSuggestions = nil
+	game.output = display
+	game.inputReader = bufio.NewReader(input)
+	game.ramSaveSlot = make(map[string]gameSnapshot)
+	game.saveRoot = saveRoot
+	game.player = player
+	return player
+}
+
+// Play something in the world, which is the player's while it's played,
+// except when the game waits for something
+func (player *worldPlayer) run(play func()) {
+	world := player.world
+	world.mutex.Lock()
+	defer world.mutex.Unlock()
+	player.enterWorld()
+	defer player.leaveWorld()
+	player.game.run(play)
+}
+
+// Bring the world into the engine, as the player sees it
+func (player *worldPlayer) enterWorld() {
+	game := player.game
+	world := player.world
+	game.objectLocation = append([]int(nil), world.objectLocation...)
+	for object, carrier := range world.carriedBy {
+		if carrier == player {
+			game.objectLocation[object] = ROOM_INVENTORY
+		}
+	}
+	game.statusFlag = append([]bool(nil), world.statusFlag...)
+	game.counterRegister = world.counterRegister
+	game.alternateCounter = append([]int(nil), world.alternateCounter...)
+	game.prngState = world.prngState
+}
+
+// Put what the player's turn changed back into the world
+func (player *worldPlayer) leaveWorld() {
+	game := player.game
+	world := player.world
+	for object, location := range game.objectLocation {
+		if location == ROOM_INVENTORY {
+			world.carriedBy[object] = player
+			location = ROOM_CARRIED_BY_OTHERS
+		} else if location != ROOM_CARRIED_BY_OTHERS {
+			delete(world.carriedBy, object)
+		}
+		world.objectLocation[object] = location
+	}
+	world.statusFlag = append([]bool(nil), game.statusFlag...)
+	world.counterRegister = game.counterRegister
+	world.alternateCounter = append([]int(nil), game.alternateCounter...)
+	world.prngState = game.prngState
+}
+
+func (game *gameEngine) inSharedWorld() bool {
+	return game.player != nil
+}
+
+// Tell the other players in a room something. It's queued for each of them
+// without waiting, so a player who has stopped reading doesn't hold up the
+// one whose turn it is
+func (player *worldPlayer) tellRoom(room int, format string, arguments ...interface{}) {
+	for other := range player.world.players {
+		if other != player && other.game.currentRoom == room {
+			fmt.Fprintf(other.game.output, localize(format), arguments...)
+		}
+	}
+}
+
+func (player *worldPlayer) showOthersHere() {
+	game := player.game
+	for other := range player.world.players {
+		if other != player && other.game.currentRoom == game.currentRoom {
+			fmt.Fprintf(game.output, localize("%s is here\n"), other.name)
+		}
+	}
+}
+
+func (game *gameEngine) objectsCarried() map[int]bool {
+	carried := make(map[int]bool)
+	for object, location := range game.objectLocation {
+		if location == ROOM_INVENTORY {
+			carried[object] = true
+		}
+	}
+	return carried
+}
+
+// Tell the others what the player did in a turn
+func (player *worldPlayer) announceTurn(room int, carried map[int]bool) {
+	game := player.game
+	for object, location := range game.objectLocation {
+		item := strings.ToLower(game.stripNounFromObjectDescription(object))
+		if location == ROOM_INVENTORY && !carried[object] {
+			player.tellRoom(room, "%s picks up the %s\n", player.name, item)
+		} else if carried[object] && location == game.currentRoom {
+			player.tellRoom(game.currentRoom, "%s drops the %s\n", player.name, item)
+		}
+	}
+	if game.currentRoom != room {
+		player.tellRoom(room, "%s leaves\n", player.name)
+		player.tellRoom(game.currentRoom, "%s arrives\n", player.name)
+		player.showOthersHere()
+	}
+}
+
+// Play the player's part of the world, the way a game is played at a
+// terminal, until the input runs out
+func (player *worldPlayer) play() {
+	game := player.game
+	fmt.Fprintln(game.output, localize("What's your name?"))
+	name, inputAvailable := game.readInputLine()
+	if !inputAvailable {
+		game.exitGame()
+	}
+	player.name = strings.TrimSpace(name)
+	if player.name == "" {
+		player.name = localize("Someone")
+	}
+	if nameRunes := []rune(player.name); len(nameRunes) > MAX_PLAYER_NAME_LENGTH {
+		player.name = string(nameRunes[:MAX_PLAYER_NAME_LENGTH])
+	}
+	player.world.players[player] = true
+	fmt.Fprintln(game.output)
+
+	game.showRoomDescription()
+	player.tellRoom(game.currentRoom, "%s arrives\n", player.name)
+	player.showOthersHere()
+
+	// What the others are told is found by comparing the world before and
+	// after each turn
+	var room int
+	var carried map[int]bool
+	game.playInteractively(func() {
+		room = game.currentRoom
+		carried = game.objectsCarried()
+	}, func() {
+		player.announceTurn(room, carried)
+	})
+}
+
+// Leave what the player carries where they are, so the others can find it
+func (player *worldPlayer) quit() {
+	game := player.game
+	for object, location := range game.objectLocation {
+		if location == ROOM_INVENTORY {
+			game.objectLocation[object] = game.currentRoom
+		}
+	}
+	if player.world.players[player] {
+		player.tellRoom(game.currentRoom, "%s has left the game\n", player.name)
+	}
+	delete(player.world.players, player)
+}
+
+// Let a player join the world, until they quit or their input runs out
+func playInSharedWorld(world *sharedWorld, display glkDisplay, input io.Reader, saveRoot string) {
+	player := world.newPlayer(display, input, saveRoot)
+	defer player.run(player.quit)
+	player.run(player.play)
+}
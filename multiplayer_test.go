@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Input that tells the test each time the game waits for a line, so that the
+// test decides who plays when
+type turnInput struct {
+	waiting chan struct{}
+	lines   chan string
+}
+
+func newTurnInput() *turnInput {
+	return &turnInput{waiting: make(chan struct{}), lines: make(chan string)}
+}
+
+func (input *turnInput) Read(text []byte) (int, error) {
+	input.waiting <- struct{}{}
+	line, more := <-input.lines
+	if !more {
+		return 0, io.EOF
+	}
+	return copy(text, line+"\n"), nil
+}
+
+// Type a line, and wait for the game to ask for the next one
+func (input *turnInput) typeLine(line string) {
+	input.lines <- line
+	<-input.waiting
+}
+
+// One player is asked which key to take while the other takes one of them.
+// The key that's been taken can't be taken again
+func TestSharedWorldObjectTakenDuringQuestion(t *testing.T) {
+	world, err := newSharedWorld(filepath.Join("testdata", "adventure.dat"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var outputs [2]bytes.Buffer
+	var inputs [2]*turnInput
+	finished := make(chan struct{})
+	for i := range inputs {
+		inputs[i] = newTurnInput()
+		go func(i int) {
+			playInSharedWorld(world, terminalDisplay{&outputs[i]}, inputs[i], t.TempDir())
+			finished <- struct{}{}
+		}(i)
+		<-inputs[i].waiting
+		inputs[i].typeLine([]string{"Al", "Bo"}[i])
+	}
+
+	inputs[0].typeLine("GET KEY")
+	inputs[1].typeLine("GET RUSTY KEY")
+	inputs[0].typeLine("RUSTY")
+
+	world.mutex.Lock()
+	var rustyKey int
+	for object, description := range world.start.objectDescription {
+		if strings.HasPrefix(description, "rusty Key") {
+			rustyKey = object
+		}
+	}
+	carrier := "nobody"
+	if player := world.carriedBy[rustyKey]; player != nil {
+		carrier = player.name
+	}
+	world.mutex.Unlock()
+	if carrier != "Bo" {
+		t.Errorf("the rusty key is carried by %s, wanted Bo", carrier)
+	}
+
+	for i := range inputs {
+		close(inputs[i].lines)
+		<-finished
+	}
+	if text := outputs[0].String(); !strings.Contains(text, "Which one?") || !strings.HasSuffix(strings.TrimSpace(text), "I don't see it here\nTell me what to do") {
+		t.Errorf("Al was told %q, wanted the rusty key not to be found", text)
+	}
+	if text := outputs[0].String(); !strings.Contains(text, "Bo picks up the rusty key") {
+		t.Errorf("Al was told %q, wanted to be told Bo picked up the rusty key", text)
+	}
+}
+
+// A player whose connection stops taking what's sent to them doesn't hold up
+// the others, and is disconnected
+func TestSharedWorldStalledPlayer(t *testing.T) {
+	world, err := newSharedWorld(filepath.Join("testdata", "adventure.dat"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, client := net.Pipe()
+	defer client.Close()
+	output := newTelnetOutput(server)
+	inputs := [2]*turnInput{newTurnInput(), newTurnInput()}
+	finished := make(chan struct{})
+	go func() {
+		playInSharedWorld(world, terminalDisplay{io.Discard}, inputs[0], t.TempDir())
+		finished <- struct{}{}
+	}()
+	go func() {
+		playInSharedWorld(world, newTelnetScreen(output), inputs[1], t.TempDir())
+		output.close()
+		finished <- struct{}{}
+	}()
+	for i, name := range []string{"Al", "Bo"} {
+		<-inputs[i].waiting
+		inputs[i].typeLine(name)
+	}
+
+	// Each turn tells Bo something, which is never read
+	for turn := 0; turn < TELNET_OUTPUT_BUFFER; turn++ {
+		inputs[0].typeLine("GET BRASS KEY")
+		inputs[0].typeLine("DROP KEY")
+	}
+	if _, err := fmt.Fprintln(output, "still there?"); err == nil {
+		t.Error("Bo is still connected after not reading")
+	}
+
+	for i := range inputs {
+		close(inputs[i].lines)
+		<-finished
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import "strings"
+
+// IT and THEM stand for the last noun the player used, so that GET LAMP can
+// be followed by LIGHT IT. Games that have these words in their own
+// vocabulary keep them.
+
+var pronouns = []string{"IT", "THEM"}
+
+// Put the last noun in place of a pronoun in the input
+func (game *gameEngine) resolvePronoun() {
+	if game.lastNoun != "" && game.isPronoun(game.extractedInputWords[1]) {
+		game.extractedInputWords[1] = game.lastNoun
+	}
+}
+
+func (game *gameEngine) isPronoun(word string) bool {
+	word = foldCase(word)
+	if game.isVocabularyNoun(word) {
+		return false
+	}
+	for _, pronoun := range pronouns {
+		if word == pronoun {
+			return true
+		}
+	}
+	return false
+}
+
+// Remember the noun of a command that was understood. Directions aren't
+// things that can be referred to later
+func (game *gameEngine) rememberNoun() {
+	noun := game.extractedInputWords[1]
+	if noun != "" && (game.foundWord[1] > DIRECTION_NOUNS || game.isObjectNoun(noun)) {
+		game.lastNoun = noun
+	}
+}
+
+func (game *gameEngine) isVocabularyNoun(noun string) bool {
+	for _, words := range game.listOfVerbsAndNouns {
+		if foldCase(strings.TrimPrefix(words[1], "*")) == noun {
+			return true
+		}
+	}
+	return false
+}
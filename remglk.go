@@ -0,0 +1,341 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// With --remglk, the interpreter talks to a front-end or a test tool in the
+// JSON protocol of RemGlk instead of to a terminal. The room is shown in a
+// grid window at the top, with the status line in a grid window below it, and
+// the messages go to a buffer window under both. Whenever the game waits for a
+// line of input, everything that changed is sent as an update, and the answer
+// is expected as a line event.
+
+const (
+	REMGLK_ROOM_WINDOW    int = 1
+	REMGLK_STATUS_WINDOW  int = 2
+	REMGLK_MESSAGE_WINDOW int = 3
+	REMGLK_MAX_LINE_INPUT int = 255
+)
+
+var (
+	remglkMode   bool
+	remglkActive bool
+	remglk       remglkScreen
+)
+
+// What's known about the front-end and what it's been sent
+type remglkScreen struct {
+	decoder    *json.Decoder
+	encoder    *json.Encoder
+	generation int
+	metrics    remglkMetrics
+	arranged   bool
+	cleared    bool
+	continuing bool
+	messages   []remglkSpan
+	style      glkStyle
+	roomText   string
+	statusText string
+	roomLines  []string
+	statusLine string
+	roomHeight int
+	columns    int
+}
+
+type remglkMetrics struct {
+	Width      float64 `json:"width"`
+	Height     float64 `json:"height"`
+	CharWidth  float64 `json:"charwidth"`
+	CharHeight float64 `json:"charheight"`
+}
+
+type remglkEvent struct {
+	Type    string         `json:"type"`
+	Gen     int            `json:"gen"`
+	Window  int            `json:"window"`
+	Value   string         `json:"value"`
+	Metrics *remglkMetrics `json:"metrics"`
+}
+
+type remglkWindow struct {
+	Id         int     `json:"id"`
+	Type       string  `json:"type"`
+	Rock       int     `json:"rock"`
+	Left       float64 `json:"left"`
+	Top        float64 `json:"top"`
+	Width      float64 `json:"width"`
+	Height     float64 `json:"height"`
+	GridWidth  int     `json:"gridwidth,omitempty"`
+	GridHeight int     `json:"gridheight,omitempty"`
+}
+
+type remglkSpan struct {
+	Style string `json:"style"`
+	Text  string `json:"text"`
+}
+
+type remglkParagraph struct {
+	Append  bool         `json:"append,omitempty"`
+	Content []remglkSpan `json:"content,omitempty"`
+}
+
+type remglkGridLine struct {
+	Line    int          `json:"line"`
+	Content []remglkSpan `json:"content"`
+}
+
+type remglkContent struct {
+	Id    int               `json:"id"`
+	Clear bool              `json:"clear,omitempty"`
+	Text  []remglkParagraph `json:"text,omitempty"`
+	Lines []remglkGridLine  `json:"lines,omitempty"`
+}
+
+type remglkInput struct {
+	Id     int    `json:"id"`
+	Gen    int    `json:"gen"`
+	Type   string `json:"type"`
+	MaxLen int    `json:"maxlen"`
+}
+
+type remglkUpdate struct {
+	Type    string          `json:"type"`
+	Gen     int             `json:"gen"`
+	Windows []remglkWindow  `json:"windows,omitempty"`
+	Content []remglkContent `json:"content,omitempty"`
+	Input   []remglkInput   `json:"input,omitempty"`
+	Disable bool            `json:"disable,omitempty"`
+}
+
+// Everything the game prints goes to the message window, and the room and the
+// status line are kept until the next update
+type remglkDisplay struct{}
+
+func (display remglkDisplay) Write(text []byte) (int, error) {
+	style := glkStyleName[remglk.style]
+	if last := len(remglk.messages) - 1; last >= 0 && remglk.messages[last].Style == style {
+		remglk.messages[last].Text += formatText(string(text))
+	} else {
+		remglk.messages = append(remglk.messages, remglkSpan{Style: style, Text: formatText(string(text))})
+	}
+	return len(text), nil
+}
+
+func (display remglkDisplay) setStyle(style glkStyle) {
+	remglk.style = style
+}
+
+func (display remglkDisplay) hasWindow(window glkWindow) bool {
+	return true
+}
+
+func (display remglkDisplay) drawWindow(window glkWindow, text string) {
+	switch window {
+	case WINDOW_ROOM:
+		remglk.roomText = text
+	case WINDOW_STATUS:
+		remglk.statusText = text
+	}
+}
+
+func (display remglkDisplay) clearWindow(window glkWindow) {
+	if window != WINDOW_MAIN {
+		display.drawWindow(window, "")
+		return
+	}
+	remglk.messages = nil
+	remglk.cleared = true
+	remglk.continuing = false
+}
+
+// The protocol has no way to show a pause, and test tools don't want to wait
+func (display remglkDisplay) delay(duration time.Duration) {}
+
+// Send an update and wait for the front-end to send a line. The input is read
+// as events, by the decoder set up for it when the front-end started. Changes
+// to the size of the window and requests to send everything again are handled
+// on the way
+func (display remglkDisplay) readLine(input *bufio.Reader) (string, error) {
+	remglk.sendUpdate(true)
+	for {
+		var event remglkEvent
+		if err := remglk.decoder.Decode(&event); err != nil {
+			// A front-end that can't be understood is taken to be gone
+			if !errors.Is(err, io.EOF) {
+				fmt.Fprintf(os.Stderr, "Invalid event from the front-end: %v\n", err)
+			}
+			return "", io.EOF
+		}
+		switch event.Type {
+		case "line":
+			return event.Value + "\n", nil
+		case "arrange":
+			remglk.setMetrics(event.Metrics)
+			remglk.sendUpdate(true)
+		case "refresh":
+			remglk.arranged = false
+			remglk.sendUpdate(true)
+		}
+	}
+}
+
+// Wait for the front-end to say what it looks like, and send everything to it
+// from then on
+func (game *gameEngine) startRemGlk() error {
+	remglk = remglkScreen{
+		decoder: json.NewDecoder(game.inputReader),
+		encoder: json.NewEncoder(os.Stdout),
+		metrics: remglkMetrics{Width: float64(DEFAULT_TERMINAL_WIDTH), Height: float64(DEFAULT_TERMINAL_HEIGHT)},
+	}
+	var event remglkEvent
+	if err := remglk.decoder.Decode(&event); err != nil {
+		return fmt.Errorf("no init event from the front-end: %v", err)
+	}
+	if event.Type != "init" {
+		return fmt.Errorf("expected an init event from the front-end, got \"%s\"", event.Type)
+	}
+	remglk.setMetrics(event.Metrics)
+	remglk.generation = event.Gen
+	remglkActive = true
+	ansiEnabled = false
+	game.output = remglkDisplay{}
+	return nil
+}
+
+func (screen *remglkScreen) setMetrics(metrics *remglkMetrics) {
+	if metrics != nil {
+		screen.metrics = *metrics
+	}
+	if screen.metrics.CharWidth <= 0 {
+		screen.metrics.CharWidth = 1
+	}
+	if screen.metrics.CharHeight <= 0 {
+		screen.metrics.CharHeight = 1
+	}
+	screen.arranged = false
+}
+
+// Lay the windows out like the split-window mode does on a terminal
+func (screen *remglkScreen) windows() []remglkWindow {
+	metrics := screen.metrics
+	screen.columns = int(metrics.Width / metrics.CharWidth)
+	if screen.columns < 1 {
+		screen.columns = 1
+	}
+	rows := int(metrics.Height / metrics.CharHeight)
+	screen.roomHeight = TUI_ROOM_PANE_LINES
+	if rows-screen.roomHeight-1 < TUI_MIN_MESSAGE_LINES {
+		screen.roomHeight = (rows - 1) / 2
+	}
+	if screen.roomHeight < 1 {
+		screen.roomHeight = 1
+	}
+	roomHeight := float64(screen.roomHeight) * metrics.CharHeight
+	messageTop := roomHeight + metrics.CharHeight
+	return []remglkWindow{
+		{Id: REMGLK_ROOM_WINDOW, Type: "grid", Width: metrics.Width, Height: roomHeight,
+			GridWidth: screen.columns, GridHeight: screen.roomHeight},
+		{Id: REMGLK_STATUS_WINDOW, Type: "grid", Top: roomHeight, Width: metrics.Width, Height: metrics.CharHeight,
+			GridWidth: screen.columns, GridHeight: 1},
+		{Id: REMGLK_MESSAGE_WINDOW, Type: "buffer", Top: messageTop, Width: metrics.Width, Height: metrics.Height - messageTop},
+	}
+}
+
+// The messages printed since the last update, as paragraphs of styled text.
+// The first one carries on the last line sent, unless that was finished
+func (screen *remglkScreen) messageParagraphs() []remglkParagraph {
+	var paragraphs []remglkParagraph
+	for _, span := range screen.messages {
+		for i, piece := range strings.Split(span.Text, "\n") {
+			if i > 0 {
+				// A line that ends without anything on it is an empty paragraph
+				if !screen.continuing {
+					paragraphs = append(paragraphs, remglkParagraph{})
+				}
+				screen.continuing = false
+			}
+			if piece == "" {
+				continue
+			}
+			text := remglkSpan{Style: span.Style, Text: piece}
+			if screen.continuing && len(paragraphs) > 0 {
+				last := &paragraphs[len(paragraphs)-1]
+				last.Content = append(last.Content, text)
+			} else {
+				paragraphs = append(paragraphs, remglkParagraph{Append: screen.continuing, Content: []remglkSpan{text}})
+			}
+			screen.continuing = true
+		}
+	}
+	screen.messages = nil
+	return paragraphs
+}
+
+func gridLines(lines []string, height int) []remglkGridLine {
+	var grid []remglkGridLine
+	for row := 0; row < height; row++ {
+		text := ""
+		if row < len(lines) {
+			text = lines[row]
+		}
+		grid = append(grid, remglkGridLine{Line: row, Content: []remglkSpan{{Style: "normal", Text: text}}})
+	}
+	return grid
+}
+
+// Send what changed since the last update, and ask for a line of input
+// unless the game is over
+func (screen *remglkScreen) sendUpdate(waitForInput bool) {
+	screen.generation++
+	update := remglkUpdate{Type: "update", Gen: screen.generation}
+	arranging := !screen.arranged
+	if arranging {
+		update.Windows = screen.windows()
+		screen.arranged = true
+	}
+
+	roomLines := wrapLines(strings.TrimRight(screen.roomText, "\n"), screen.columns)
+	if len(roomLines) > screen.roomHeight {
+		roomLines = roomLines[:screen.roomHeight]
+		roomLines[screen.roomHeight-1] = "..."
+	}
+	status := formatText(screen.statusText)
+	if arranging || strings.Join(roomLines, "\n") != strings.Join(screen.roomLines, "\n") {
+		update.Content = append(update.Content, remglkContent{Id: REMGLK_ROOM_WINDOW, Lines: gridLines(roomLines, screen.roomHeight)})
+		screen.roomLines = roomLines
+	}
+	if arranging || status != screen.statusLine {
+		update.Content = append(update.Content, remglkContent{Id: REMGLK_STATUS_WINDOW, Lines: gridLines([]string{status}, 1)})
+		screen.statusLine = status
+	}
+
+	paragraphs := screen.messageParagraphs()
+	if screen.cleared || len(paragraphs) > 0 {
+		update.Content = append(update.Content, remglkContent{Id: REMGLK_MESSAGE_WINDOW, Clear: screen.cleared, Text: paragraphs})
+		screen.cleared = false
+	}
+
+	if waitForInput {
+		update.Input = []remglkInput{{Id: REMGLK_MESSAGE_WINDOW, Gen: screen.generation, Type: "line", MaxLen: REMGLK_MAX_LINE_INPUT}}
+	} else {
+		update.Disable = true
+	}
+	screen.encoder.Encode(update)
+}
+
+// Send what's left when the game ends
+func (game *gameEngine) closeRemGlk() {
+	if remglkActive {
+		game.updateWindows()
+		remglk.sendUpdate(false)
+		remglkActive = false
+	}
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	DEFAULT_SAVE_SLOT   string = "default"
+	ROOM_NAME_LENGTH    int    = 40
+	SAVE_FILE_EXTENSION string = ".sav"
+)
+
+var saveSlotPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Directory where the interpreter keeps its files, following the XDG base
+// directory specification
+func dataDirectory() (string, error) {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dataHome, "GoVerbYourNoun"), nil
+}
+
+// Directory where save slots for the loaded adventure are kept
+func (game *gameEngine) saveDirectory() (string, error) {
+	if game.saveRoot != "" {
+		return game.saveRoot, nil
+	}
+	directory, err := dataDirectory()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(directory, fmt.Sprintf("adventure-%d", game.adventureNumber)), nil
+}
+
+// Translate a slot name into a file path. Anything that looks like a path is
+// used as it is, so that save files can still be kept wherever the player wants,
+// except by players of a server, who only get the slots of their own directory
+func (game *gameEngine) saveFilePath(slot string) (string, error) {
+	if slot == "" {
+		slot = DEFAULT_SAVE_SLOT
+	}
+	if strings.ContainsAny(slot, `/\`) && game.saveRoot == "" {
+		return slot, nil
+	}
+	if !saveSlotPattern.MatchString(slot) {
+		return "", fmt.Errorf("invalid slot name \"%s\", use only letters, digits, - and _", slot)
+	}
+	directory, err := game.saveDirectory()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(directory, slot+SAVE_FILE_EXTENSION), nil
+}
+
+func (game *gameEngine) askForSaveSlot() string {
+	fmt.Fprintf(game.output, localize("Name of save slot (Enter for \"%s\"):\n"), DEFAULT_SAVE_SLOT)
+	slot, _ := game.readInputLine()
+	return strings.TrimSpace(slot)
+}
+
+func boolSliceToIntSlice(boolSlice []bool) []int {
+	intSlice := make([]int, len(boolSlice))
+	for i, val := range boolSlice {
+		if val {
+			intSlice[i] = 1
+		} else {
+			intSlice[i] = 0
+		}
+	}
+	return intSlice
+}
+
+func intToBool(num int) bool {
+	if num == FALSE_VALUE {
+		return false
+	} else {
+		return true
+	}
+}
+
+func (game *gameEngine) encodeSaveData(snapshot gameSnapshot) []int {
+	saveData := []int{game.adventureVersion, game.adventureNumber, snapshot.currentRoom}
+	saveData = append(saveData, snapshot.alternateRoom...)
+	saveData = append(saveData, snapshot.counterRegister)
+	saveData = append(saveData, snapshot.alternateCounter...)
+	saveData = append(saveData, snapshot.objectLocation...)
+	saveData = append(saveData, boolSliceToIntSlice(snapshot.statusFlag)...)
+	saveData = append(saveData, snapshot.turnCounter)
+	return saveData
+}
+
+func (game *gameEngine) decodeSaveData(saveData []int) (gameSnapshot, error) {
+	snapshot := game.takeSnapshot()
+
+	// The turn counter at the end is missing in older save files
+	requiredLength := 4 + len(snapshot.alternateRoom) + len(snapshot.alternateCounter) +
+		len(snapshot.objectLocation) + len(snapshot.statusFlag)
+	if len(saveData) < requiredLength {
+		return snapshot, errors.New("Save file is incomplete")
+	}
+	if len(saveData) > requiredLength+1 {
+		return snapshot, errors.New("Save file is for a game with more flags, counters or objects")
+	}
+	if saveData[0] != game.adventureVersion {
+		return snapshot, errors.New("Invalid savegame version")
+	}
+	if saveData[1] != game.adventureNumber {
+		return snapshot, errors.New("Invalid savegame adventure number")
+	}
+
+	next := saveData[2:]
+	snapshot.currentRoom, next = next[0], next[1:]
+	next = next[copy(snapshot.alternateRoom, next):]
+	snapshot.counterRegister, next = next[0], next[1:]
+	next = next[copy(snapshot.alternateCounter, next):]
+	next = next[copy(snapshot.objectLocation, next):]
+	for i := range snapshot.statusFlag {
+		snapshot.statusFlag[i] = intToBool(next[i])
+	}
+	next = next[len(snapshot.statusFlag):]
+	snapshot.turnCounter = 0
+	if len(next) > 0 {
+		snapshot.turnCounter = next[0]
+	}
+
+	// A room that the game doesn't have would stop it for good
+	if !game.isRoom(snapshot.currentRoom) {
+		return snapshot, fmt.Errorf("Save file has the player in room %d, which the game doesn't have", snapshot.currentRoom)
+	}
+	for _, room := range snapshot.alternateRoom {
+		if !game.isRoom(room) {
+			return snapshot, fmt.Errorf("Save file has a room %d, which the game doesn't have", room)
+		}
+	}
+	for object, location := range snapshot.objectLocation {
+		if location != ROOM_INVENTORY && !game.isRoom(location) {
+			return snapshot, fmt.Errorf("Save file has object %d in room %d, which the game doesn't have", object, location)
+		}
+	}
+	return snapshot, nil
+}
+
+func (game *gameEngine) isRoom(room int) bool {
+	return room >= 0 && room < len(game.roomDescription)
+}
+
+func (game *gameEngine) writeSaveFile(saveFileName string, snapshot gameSnapshot) error {
+	if err := os.MkdirAll(filepath.Dir(saveFileName), 0755); err != nil {
+		return err
+	}
+	saveFile, err := os.Create(saveFileName)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(saveFile)
+	for _, data := range game.encodeSaveData(snapshot) {
+		fmt.Fprintln(writer, data)
+	}
+	if err := writer.Flush(); err != nil {
+		saveFile.Close()
+		return err
+	}
+	return saveFile.Close()
+}
+
+func (game *gameEngine) readSaveFile(saveFileName string) (gameSnapshot, error) {
+	saveFile, err := os.Open(saveFileName)
+	if err != nil {
+		return gameSnapshot{}, err
+	}
+	defer saveFile.Close()
+
+	var saveData []int
+	scanner := bufio.NewScanner(saveFile)
+	for scanner.Scan() {
+		var num int
+		fmt.Sscan(scanner.Text(), &num)
+		saveData = append(saveData, num)
+	}
+	if err := scanner.Err(); err != nil {
+		return gameSnapshot{}, err
+	}
+	return game.decodeSaveData(saveData)
+}
+
+func (game *gameEngine) saveGame() bool {
+	slot := game.askForSaveSlot()
+	saveFileName, err := game.saveFilePath(slot)
+	if err != nil {
+		fmt.Fprintf(game.output, localize("Couldn't save game: %v\n"), err)
+		return false
+	}
+
+	if _, err := os.Stat(saveFileName); err == nil {
+		if !game.confirm(fmt.Sprintf(localize("\"%s\" already exists. Overwrite it? (Y/N)"), slot)) {
+			fmt.Fprintln(game.output, localize("Game not saved"))
+			return false
+		}
+	}
+
+	if err := game.writeSaveFile(saveFileName, game.takeSnapshot()); err != nil {
+		fmt.Fprintf(game.output, localize("Couldn't save game: %v\n"), err)
+		return false
+	}
+	fmt.Fprintln(game.output, localize("Game saved"))
+	return true
+}
+
+func (game *gameEngine) loadGame() bool {
+	slot := game.askForSaveSlot()
+	saveFileName, err := game.saveFilePath(slot)
+	if err != nil {
+		fmt.Fprintf(game.output, localize("Couldn't load game: %v\n"), err)
+		return false
+	}
+
+	snapshot, err := game.readSaveFile(saveFileName)
+	if errors.Is(err, os.ErrNotExist) {
+		fmt.Fprintf(game.output, localize("Couldn't load \"%s\". Doesn't exist!\n"), slot)
+		return false
+	} else if err != nil {
+		fmt.Fprintln(game.output, err)
+		return false
+	}
+
+	game.restoreSnapshot(snapshot)
+	return true
+}
+
+// Name of a room, short enough to fit in a listing
+func (game *gameEngine) roomName(room int) string {
+	if room < 0 || room >= len(game.roomDescription) {
+		return "?"
+	}
+	name := strings.Join(strings.Fields(strings.TrimPrefix(game.roomDescription[room], "*")), " ")
+	if runes := []rune(name); len(runes) > ROOM_NAME_LENGTH {
+		name = string(runes[:ROOM_NAME_LENGTH-3]) + "..."
+	}
+	return name
+}
+
+// Names of the save files in a directory, in order
+func saveSlotFiles(directory string) ([]string, error) {
+	entries, err := os.ReadDir(directory)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	var slots []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), SAVE_FILE_EXTENSION) {
+			slots = append(slots, entry.Name())
+		}
+	}
+	sort.Strings(slots)
+	return slots, nil
+}
+
+// Show the save slots of the loaded adventure
+func (game *gameEngine) listSaveSlots() {
+	directory, err := game.saveDirectory()
+	if err != nil {
+		fmt.Fprintf(game.output, localize("Couldn't list saved games: %v\n"), err)
+		return
+	}
+	slots, err := saveSlotFiles(directory)
+	if err != nil {
+		fmt.Fprintf(game.output, localize("Couldn't list saved games: %v\n"), err)
+		return
+	}
+	if len(slots) == 0 {
+		fmt.Fprintln(game.output, localize("No saved games"))
+		return
+	}
+
+	fmt.Fprintf(game.output, localize("Saved games in %s:\n"), directory)
+	for _, fileName := range slots {
+		slot := strings.TrimSuffix(fileName, SAVE_FILE_EXTENSION)
+		saveFileName := filepath.Join(directory, fileName)
+		info, err := os.Stat(saveFileName)
+		if err != nil {
+			continue
+		}
+		snapshot, err := game.readSaveFile(saveFileName)
+		if err != nil {
+			fmt.Fprintf(game.output, "%-16s (%v)\n", slot, err)
+			continue
+		}
+		fmt.Fprintf(game.output, localize("%-16s %-*s %5d turns  %s\n"), slot, ROOM_NAME_LENGTH, game.roomName(snapshot.currentRoom),
+			snapshot.turnCounter, info.ModTime().Format("2006-01-02 15:04"))
+	}
+}
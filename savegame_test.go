@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// A game written to a save file reads back the same, and so does one saved
+// before the turn counter was added to the end
+func TestSaveFileRoundTrip(t *testing.T) {
+	game := loadFixture(t, "adventure.dat")
+	game.saveRoot = t.TempDir()
+	game.initializeGameState()
+	game.playCommands("GET LAMP\nS\n")
+	game.statusFlag[1] = true
+	game.alternateRoom[2] = 3
+	game.counterRegister = 7
+	game.alternateCounter[4] = 9
+	saved := game.takeSnapshot()
+
+	saveFileName, err := game.saveFilePath("slot1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := game.writeSaveFile(saveFileName, saved); err != nil {
+		t.Fatal(err)
+	}
+	read, err := game.readSaveFile(saveFileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The random numbers go on as they are, and aren't saved
+	read.prngState = saved.prngState
+	if !reflect.DeepEqual(read, saved) {
+		t.Errorf("read back %+v, wanted %+v", read, saved)
+	}
+
+	older := game.encodeSaveData(saved)
+	older = older[:len(older)-1]
+	read, err = game.decodeSaveData(older)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.turnCounter != 0 || read.currentRoom != saved.currentRoom {
+		t.Errorf("save without a turn counter read back in room %d after %d turns, wanted room %d after 0",
+			read.currentRoom, read.turnCounter, saved.currentRoom)
+	}
+}
+
+// SAVE GAME asks before overwriting a slot, LOAD GAME goes back to it, and
+// SAVES lists it
+func TestSaveSlots(t *testing.T) {
+	game := loadFixture(t, "adventure.dat")
+	game.saveRoot = t.TempDir()
+	game.initializeGameState()
+	clearing := game.currentRoom
+
+	var text bytes.Buffer
+	game.output = terminalDisplay{&text}
+	game.playCommands("SAVE GAME\nslot1\nS\nSAVE GAME\nslot1\nN\nLOAD GAME\nslot1\n")
+	if !strings.Contains(text.String(), "\"slot1\" already exists. Overwrite it? (Y/N)") || !strings.Contains(text.String(), "Game not saved") {
+		t.Errorf("%q doesn't ask before overwriting the slot", text.String())
+	}
+	if game.currentRoom != clearing {
+		t.Errorf("in room %d after LOAD GAME, wanted the forest clearing", game.currentRoom)
+	}
+
+	text.Reset()
+	game.playCommands("SAVES\nLOAD GAME\nslot2\nSAVE GAME\n../slot3\n")
+	for _, wanted := range []string{"slot1", "forest clearing", "1 turns", "Couldn't load \"slot2\". Doesn't exist!", "Couldn't save game: invalid slot name"} {
+		if !strings.Contains(text.String(), wanted) {
+			t.Errorf("%q doesn't have %q", text.String(), wanted)
+		}
+	}
+	if files, _ := os.ReadDir(game.saveRoot); len(files) != 1 || files[0].Name() != "slot1"+SAVE_FILE_EXTENSION {
+		t.Errorf("got save files %v, wanted only slot1", files)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(game.saveRoot), "slot3"+SAVE_FILE_EXTENSION)); err == nil {
+		t.Error("a slot name that's a path was saved outside the save directory")
+	}
+}
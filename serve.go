@@ -0,0 +1,455 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The serve command runs games for other programs over HTTP. Each session is
+// a game of its own, played by posting commands and getting back what the
+// game printed along with the room, the inventory and whether the game is
+// over.
+//
+//	GET    /games                 the games in the library directory
+//	POST   /sessions              start a game: {"game": "adv01.dat"}
+//	GET    /sessions/{id}         the state of the game
+//	POST   /sessions/{id}/command play commands: {"command": "get lamp"}
+//	GET    /sessions/{id}/save    the game saved: {"save": "..."}
+//	POST   /sessions/{id}/restore continue a saved game: {"save": "..."}
+//	DELETE /sessions/{id}         end the session
+//	GET    /play?game=adv01.dat   play a game over a WebSocket
+//	GET    /                      a browser client that plays over /play
+//
+// Every line of a command is played as a turn, unless the game reads it as
+// the answer to a question, so "QUIT\nY" quits. Sessions that get no requests
+// for --idle-timeout are ended, so that clients that go away don't keep them.
+
+const (
+	DEFAULT_LISTEN_ADDRESS string        = "localhost:8080"
+	DEFAULT_MAX_SESSIONS   int           = 100
+	MAX_REQUEST_SIZE       int64         = 64 << 10
+	SESSION_ID_BYTES       int           = 16
+	SESSIONS_DIRECTORY     string        = "sessions"
+	SESSION_SWEEP_INTERVAL time.Duration = time.Minute
+)
+
+var (
+	allowedOrigins     string
+	maxSessions        int
+	serverLibrary      string
+	sessions           = make(map[string]*session)
+	sessionsMutex      sync.Mutex
+	startingSessions   int
+	listenAddress      string
+	errGameIsOver      = errors.New("the game is over")
+	errNoSuchGame      = errors.New("no such game")
+	errTooManySessions = errors.New("too many sessions")
+)
+
+// One game played through the server
+type session struct {
+	id       string
+	game     string
+	engine   *gameEngine
+	screen   *sessionScreen
+	gameOver bool
+	mutex    sync.Mutex // Requests for the same session are handled one at a time
+	lastUsed time.Time  // When it was last asked for, kept with sessionsMutex held
+}
+
+// Collects what the game prints during a request. Clearing the screen throws
+// away what was printed before, like it would on a screen, and pauses are left
+// to the client
+type sessionScreen struct {
+	text strings.Builder
+}
+
+func (screen *sessionScreen) Write(text []byte) (int, error) {
+	return screen.text.Write(text)
+}
+
+func (screen *sessionScreen) setStyle(style glkStyle) {}
+
+func (screen *sessionScreen) hasWindow(window glkWindow) bool {
+	return window == WINDOW_MAIN
+}
+
+func (screen *sessionScreen) drawWindow(window glkWindow, text string) {}
+
+func (screen *sessionScreen) clearWindow(window glkWindow) {
+	if window == WINDOW_MAIN {
+		screen.text.Reset()
+	}
+}
+
+func (screen *sessionScreen) delay(duration time.Duration) {}
+
+func (screen *sessionScreen) readLine(input *bufio.Reader) (string, error) {
+	return input.ReadString('\n')
+}
+
+// What the server answers about a session
+type sessionResponse struct {
+	Id        string   `json:"id"`
+	Game      string   `json:"game"`
+	Output    string   `json:"output"`
+	Room      string   `json:"room"`
+	Inventory []string `json:"inventory"`
+	Turns     int      `json:"turns"`
+	GameOver  bool     `json:"gameOver"`
+}
+
+type commandRequest struct {
+	Game    string `json:"game"`
+	Command string `json:"command"`
+	Save    string `json:"save"`
+}
+
+func serve(arguments []string) {
+	options := flag.NewFlagSet("serve", flag.ExitOnError)
+	options.StringVar(&listenAddress, "listen", DEFAULT_LISTEN_ADDRESS, "Address to listen on")
+	options.StringVar(&serverLibrary, "library", ".", "Directory with the games that can be played")
+	options.IntVar(&maxSessions, "max-sessions", DEFAULT_MAX_SESSIONS, "Number of sessions that can be open at once")
+	options.StringVar(&allowedOrigins, "allow-origins", "", "Comma separated origins of other sites whose pages may play, or * for any")
+	options.DurationVar(&idleTimeout, "idle-timeout", DEFAULT_IDLE_TIMEOUT, "End sessions that get no requests for this long (0 never does)")
+	options.StringVar(&selectedLanguage, "lang", "", "Language of the interpreter messages: en or de")
+	options.BoolVar(&fuzzyMatching, "fuzzy", false, "Understand words with small typing mistakes")
+	options.Parse(arguments)
+
+	setNoiseWords(DEFAULT_NOISE_WORDS)
+	if err := selectLanguage(); err != nil {
+		log.Fatal(err)
+	}
+
+	if idleTimeout > 0 {
+		go sweepSessions()
+	}
+	log.Printf("Serving games from %s on http://%s/", serverLibrary, listenAddress)
+	log.Fatal(http.ListenAndServe(listenAddress, serverHandler()))
+}
+
+func serverHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/games", handleGames)
+	mux.HandleFunc("/sessions", handleSessions)
+	mux.HandleFunc("/sessions/", handleSession)
+	mux.HandleFunc("/play", handlePlay)
+	mux.Handle("/", webClient())
+	return mux
+}
+
+func writeJSON(writer http.ResponseWriter, status int, value interface{}) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(status)
+	json.NewEncoder(writer).Encode(value)
+}
+
+func writeError(writer http.ResponseWriter, status int, err error) {
+	writeJSON(writer, status, map[string]string{"error": err.Error()})
+}
+
+func readRequest(writer http.ResponseWriter, request *http.Request) (commandRequest, bool) {
+	var body commandRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(writer, request.Body, MAX_REQUEST_SIZE))
+	if err := decoder.Decode(&body); err != nil {
+		writeError(writer, http.StatusBadRequest, fmt.Errorf("invalid request: %v", err))
+		return body, false
+	}
+	return body, true
+}
+
+// The games that sessions can be started with
+func handleGames(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet {
+		writeError(writer, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	type gameEntry struct {
+		Game            string `json:"game"`
+		Title           string `json:"title"`
+		AdventureNumber int    `json:"adventureNumber"`
+	}
+	entries, err := scanLibrary(serverLibrary)
+	if err != nil {
+		writeError(writer, http.StatusInternalServerError, err)
+		return
+	}
+	games := []gameEntry{}
+	for _, entry := range entries {
+		games = append(games, gameEntry{filepath.Base(entry.path), entry.title, entry.adventureNumber})
+	}
+	writeJSON(writer, http.StatusOK, games)
+}
+
+func handleSessions(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		writeError(writer, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	body, ok := readRequest(writer, request)
+	if !ok {
+		return
+	}
+	newSession, err := startSession(body.Game)
+	if errors.Is(err, errNoSuchGame) {
+		writeError(writer, http.StatusNotFound, err)
+		return
+	} else if errors.Is(err, errTooManySessions) {
+		writeError(writer, http.StatusServiceUnavailable, err)
+		return
+	} else if err != nil {
+		writeError(writer, http.StatusUnprocessableEntity, err)
+		return
+	}
+	writeJSON(writer, http.StatusCreated, newSession.response())
+}
+
+// Requests for one session, at /sessions/{id} and below
+func handleSession(writer http.ResponseWriter, request *http.Request) {
+	path := strings.Split(strings.Trim(strings.TrimPrefix(request.URL.Path, "/sessions/"), "/"), "/")
+	sessionsMutex.Lock()
+	currentSession, found := sessions[path[0]]
+	if found {
+		currentSession.lastUsed = time.Now()
+	}
+	sessionsMutex.Unlock()
+	if !found {
+		writeError(writer, http.StatusNotFound, errors.New("no such session"))
+		return
+	}
+	currentSession.mutex.Lock()
+	defer currentSession.mutex.Unlock()
+	action := ""
+	if len(path) > 1 {
+		action = path[1]
+	}
+
+	switch {
+	case action == "" && request.Method == http.MethodGet:
+		writeJSON(writer, http.StatusOK, currentSession.response())
+	case action == "" && request.Method == http.MethodDelete:
+		endSession(currentSession)
+		writer.WriteHeader(http.StatusNoContent)
+	case action == "command" && request.Method == http.MethodPost:
+		body, ok := readRequest(writer, request)
+		if !ok {
+			return
+		}
+		if err := currentSession.play(body.Command); err != nil {
+			writeError(writer, http.StatusConflict, err)
+			return
+		}
+		writeJSON(writer, http.StatusOK, currentSession.response())
+	case action == "save" && request.Method == http.MethodGet:
+		writeJSON(writer, http.StatusOK, map[string]string{"save": currentSession.save()})
+	case action == "restore" && request.Method == http.MethodPost:
+		body, ok := readRequest(writer, request)
+		if !ok {
+			return
+		}
+		if err := currentSession.restore(body.Save); err != nil {
+			writeError(writer, http.StatusUnprocessableEntity, err)
+			return
+		}
+		writeJSON(writer, http.StatusOK, currentSession.response())
+	default:
+		writeError(writer, http.StatusNotFound, errors.New("unknown request"))
+	}
+}
+
+func newSessionId() string {
+	id := make([]byte, SESSION_ID_BYTES)
+	if _, err := rand.Read(id); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(id)
+}
+
+// The path of a game in the library. Only files right in the library
+// directory can be played
+func gamePath(game string) (string, error) {
+	if game == "" || filepath.Base(game) != game || strings.HasPrefix(game, ".") {
+		return "", errNoSuchGame
+	}
+	path := filepath.Join(serverLibrary, game)
+	if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() {
+		return "", errNoSuchGame
+	}
+	return path, nil
+}
+
+// Where a session keeps its saved games
+func sessionSaveRoot(id string) (string, error) {
+	dataPath, err := dataDirectory()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataPath, SESSIONS_DIRECTORY, id), nil
+}
+
+// The sessions and streams that are played or being started. Called with
+// sessionsMutex held
+func sessionsInUse() int {
+	return len(sessions) + startingSessions + streamCount
+}
+
+// Count a session that's being started, if there's room for one more
+func reserveSession() bool {
+	sessionsMutex.Lock()
+	defer sessionsMutex.Unlock()
+	if sessionsInUse() >= maxSessions {
+		return false
+	}
+	startingSessions++
+	return true
+}
+
+// Stop counting a session that's being started, adding it if it got going
+func finishStartingSession(newSession *session) {
+	sessionsMutex.Lock()
+	defer sessionsMutex.Unlock()
+	startingSessions--
+	if newSession != nil {
+		newSession.lastUsed = time.Now()
+		sessions[newSession.id] = newSession
+	}
+}
+
+// Start a game from the library
+func startSession(game string) (newSession *session, err error) {
+	path, err := gamePath(game)
+	if err != nil {
+		return nil, err
+	}
+	if !reserveSession() {
+		return nil, errTooManySessions
+	}
+	// The session returned is the one added, nil if it couldn't be started
+	defer func() { finishStartingSession(newSession) }()
+
+	id := newSessionId()
+	saveRoot, err := sessionSaveRoot(id)
+	if err != nil {
+		return nil, err
+	}
+	screen := &sessionScreen{}
+	engine, err := loadSessionGame(path, screen, saveRoot)
+	if err != nil {
+		return nil, err
+	}
+	newSession = &session{id: id, game: game, engine: engine, screen: screen}
+	newSession.gameOver = !engine.run(engine.startGame)
+	return newSession, nil
+}
+
+func endSession(oldSession *session) {
+	sessionsMutex.Lock()
+	delete(sessions, oldSession.id)
+	sessionsMutex.Unlock()
+	os.RemoveAll(oldSession.engine.saveRoot)
+}
+
+// End the sessions that have been idle for longer than --idle-timeout, every
+// now and then
+func sweepSessions() {
+	for range time.Tick(SESSION_SWEEP_INTERVAL) {
+		for _, idleSession := range idleSessions(time.Now().Add(-idleTimeout)) {
+			// A request that's still being handled is waited for
+			idleSession.mutex.Lock()
+			endSession(idleSession)
+			idleSession.mutex.Unlock()
+		}
+	}
+}
+
+// The sessions last asked for before a time, taken out so that no more
+// requests find them
+func idleSessions(before time.Time) []*session {
+	sessionsMutex.Lock()
+	defer sessionsMutex.Unlock()
+	var idle []*session
+	for id, currentSession := range sessions {
+		if currentSession.lastUsed.Before(before) {
+			delete(sessions, id)
+			idle = append(idle, currentSession)
+		}
+	}
+	return idle
+}
+
+// Play the lines of a command
+func (currentSession *session) play(command string) error {
+	if currentSession.gameOver {
+		return errGameIsOver
+	}
+	engine := currentSession.engine
+	currentSession.gameOver = !engine.run(func() {
+		engine.playCommands(command)
+	})
+	return nil
+}
+
+func (currentSession *session) save() string {
+	engine := currentSession.engine
+	var saveData []string
+	for _, value := range engine.encodeSaveData(engine.takeSnapshot()) {
+		saveData = append(saveData, strconv.Itoa(value))
+	}
+	return strings.Join(saveData, "\n")
+}
+
+func (currentSession *session) restore(save string) error {
+	var saveData []int
+	for _, field := range strings.Fields(save) {
+		value, err := strconv.Atoi(field)
+		if err != nil {
+			return fmt.Errorf("invalid save data: %v", err)
+		}
+		saveData = append(saveData, value)
+	}
+	engine := currentSession.engine
+	snapshot, err := engine.decodeSaveData(saveData)
+	if err != nil {
+		return err
+	}
+	engine.pushUndoSnapshot(engine.takeSnapshot())
+	engine.restoreSnapshot(snapshot)
+	engine.showRoomDescription()
+	currentSession.gameOver = false
+	return nil
+}
+
+// Everything a client needs to show the game. What the game printed is
+// handed out once
+func (currentSession *session) response() sessionResponse {
+	response := sessionResponse{
+		Id:       currentSession.id,
+		Game:     currentSession.game,
+		GameOver: currentSession.gameOver,
+	}
+	engine := currentSession.engine
+	response.Room = formatText(engine.roomDescriptionText())
+	response.Inventory = []string{}
+	for object, location := range engine.objectLocation {
+		if location == ROOM_INVENTORY {
+			response.Inventory = append(response.Inventory, engine.stripNounFromObjectDescription(object))
+		}
+	}
+	response.Turns = engine.turnCounter
+	response.Output = formatText(currentSession.screen.text.String())
+	currentSession.screen.text.Reset()
+	return response
+}
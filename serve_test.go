@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func postJSON(t *testing.T, server *httptest.Server, path string, body commandRequest, response interface{}) int {
+	t.Helper()
+	data, _ := json.Marshal(body)
+	reply, err := http.Post(server.URL+path, "application/json", bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reply.Body.Close()
+	if response != nil {
+		json.NewDecoder(reply.Body).Decode(response)
+	}
+	return reply.StatusCode
+}
+
+// A save with a room or an object location the game doesn't have, or with
+// the wrong number of values, is turned down and the session goes on
+func TestRestoreRejectsInvalidSave(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	serverLibrary = "testdata"
+	maxSessions = DEFAULT_MAX_SESSIONS
+	server := httptest.NewServer(serverHandler())
+	defer server.Close()
+
+	var started sessionResponse
+	if status := postJSON(t, server, "/sessions", commandRequest{Game: "adventure.dat"}, &started); status != http.StatusCreated {
+		t.Fatalf("starting a session: got status %d", status)
+	}
+	sessionsMutex.Lock()
+	currentSession := sessions[started.Id]
+	sessionsMutex.Unlock()
+	defer endSession(currentSession)
+	engine := currentSession.engine
+	saveData := engine.encodeSaveData(engine.takeSnapshot())
+	objectsAt := 4 + len(engine.alternateRoom) + len(engine.alternateCounter)
+
+	changed := func(index, value int) []int {
+		changedData := append([]int(nil), saveData...)
+		changedData[index] = value
+		return changedData
+	}
+	tests := []struct {
+		name     string
+		saveData []int
+	}{
+		{"player in a room the game doesn't have", changed(2, 9999)},
+		{"player in a negative room", changed(2, -1)},
+		{"object in a room the game doesn't have", changed(objectsAt, len(engine.roomDescription))},
+		{"object in a negative room", changed(objectsAt, -5)},
+		{"too few values", saveData[:len(saveData)-2]},
+		{"too many values", append(append([]int(nil), saveData...), 0, 0)},
+	}
+	for _, test := range tests {
+		var save []string
+		for _, value := range test.saveData {
+			save = append(save, strconv.Itoa(value))
+		}
+		path := "/sessions/" + started.Id + "/restore"
+		if status := postJSON(t, server, path, commandRequest{Save: strings.Join(save, "\n")}, nil); status != http.StatusUnprocessableEntity {
+			t.Errorf("%s: got status %d, wanted %d", test.name, status, http.StatusUnprocessableEntity)
+		}
+	}
+
+	var played sessionResponse
+	if status := postJSON(t, server, "/sessions/"+started.Id+"/command", commandRequest{Command: "LOOK"}, &played); status != http.StatusOK {
+		t.Fatalf("playing after the rejected saves: got status %d", status)
+	}
+	if played.Room != started.Room {
+		t.Errorf("got room %q after the rejected saves, wanted %q", played.Room, started.Room)
+	}
+}
+
+// Sessions that haven't been asked for since a time are taken out, and the
+// others stay
+func TestIdleSessions(t *testing.T) {
+	now := time.Now()
+	idle := &session{id: "idle", lastUsed: now.Add(-time.Hour)}
+	used := &session{id: "used", lastUsed: now}
+	sessionsMutex.Lock()
+	sessions[idle.id] = idle
+	sessions[used.id] = used
+	sessionsMutex.Unlock()
+	defer func() {
+		sessionsMutex.Lock()
+		delete(sessions, used.id)
+		sessionsMutex.Unlock()
+	}()
+
+	expired := idleSessions(now.Add(-time.Minute))
+	if len(expired) != 1 || expired[0] != idle {
+		t.Errorf("got idle sessions %v, wanted only %q", expired, idle.id)
+	}
+	sessionsMutex.Lock()
+	_, idleFound := sessions[idle.id]
+	_, usedFound := sessions[used.id]
+	sessionsMutex.Unlock()
+	if idleFound || !usedFound {
+		t.Errorf("idle session left: %v, used session left: %v, wanted false and true", idleFound, usedFound)
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bufio"
+	"embed"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Games played over a WebSocket are played like at a terminal: the game asks
+// for commands and questions are answered as they come. What the game does is
+// sent to the browser as it happens, as events:
+//
+//	{"type": "text", "text": "OK\n"}    text to show, with "style": "alert"
+//	                                    for warnings
+//	{"type": "clear"}                   clear the screen
+//	{"type": "pause", "milliseconds": 1000}
+//	{"type": "over"}                    the game has come to an end
+//
+// The browser sends each command as a text message. A browser that falls
+// STREAM_BUFFER events behind is disconnected, rather than holding up the game.
+
+const STREAM_BUFFER int = 1024
+
+var streamCount int
+
+//go:embed web
+var webFiles embed.FS
+
+type streamEvent struct {
+	Type         string `json:"type"`
+	Text         string `json:"text,omitempty"`
+	Style        string `json:"style,omitempty"`
+	Milliseconds int64  `json:"milliseconds,omitempty"`
+}
+
+// Turns what the game does into events. Pauses are left to the browser, so
+// that other games can be played in the meantime
+type streamScreen struct {
+	events     chan streamEvent
+	style      glkStyle
+	overflow   func() // Called when the browser has fallen too far behind
+	overflowed bool
+}
+
+// Queue an event without waiting for the browser. Once the queue has
+// overflowed, the events that follow are dropped
+func (screen *streamScreen) send(event streamEvent) {
+	if screen.overflowed {
+		return
+	}
+	select {
+	case screen.events <- event:
+	default:
+		screen.overflowed = true
+		screen.overflow()
+	}
+}
+
+func (screen *streamScreen) Write(text []byte) (int, error) {
+	event := streamEvent{Type: "text", Text: formatText(string(text))}
+	if screen.style != STYLE_NORMAL {
+		event.Style = glkStyleName[screen.style]
+	}
+	screen.send(event)
+	return len(text), nil
+}
+
+func (screen *streamScreen) setStyle(style glkStyle) {
+	screen.style = style
+}
+
+func (screen *streamScreen) hasWindow(window glkWindow) bool {
+	return window == WINDOW_MAIN
+}
+
+func (screen *streamScreen) drawWindow(window glkWindow, text string) {}
+
+func (screen *streamScreen) clearWindow(window glkWindow) {
+	if window == WINDOW_MAIN {
+		screen.send(streamEvent{Type: "clear"})
+	}
+}
+
+func (screen *streamScreen) delay(duration time.Duration) {
+	screen.send(streamEvent{Type: "pause", Milliseconds: duration.Milliseconds()})
+}
+
+func (screen *streamScreen) readLine(input *bufio.Reader) (string, error) {
+	return input.ReadString('\n')
+}
+
+func webClient() http.Handler {
+	files, err := fs.Sub(webFiles, "web")
+	if err != nil {
+		panic(err)
+	}
+	return http.FileServer(http.FS(files))
+}
+
+// Count a stream as a session, if there's room for one more
+func reserveStream() bool {
+	sessionsMutex.Lock()
+	defer sessionsMutex.Unlock()
+	if sessionsInUse() >= maxSessions {
+		return false
+	}
+	streamCount++
+	return true
+}
+
+func releaseStream() {
+	sessionsMutex.Lock()
+	streamCount--
+	sessionsMutex.Unlock()
+}
+
+// Browsers let any page open a WebSocket, so only pages from the server
+// itself and the origins given with --allow-origins may play. Programs that
+// aren't browsers don't send an origin
+func originAllowed(request *http.Request) bool {
+	origin := request.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if originURL, err := url.Parse(origin); err == nil && strings.EqualFold(originURL.Host, request.Host) {
+		return true
+	}
+	for _, allowed := range strings.Split(allowedOrigins, ",") {
+		allowed = strings.TrimRight(strings.TrimSpace(allowed), "/")
+		if allowed == "*" || (allowed != "" && strings.EqualFold(allowed, origin)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Play a game over a WebSocket, for as long as it's open
+func handlePlay(writer http.ResponseWriter, request *http.Request) {
+	if !originAllowed(request) {
+		writeError(writer, http.StatusForbidden, errors.New("origin not allowed"))
+		return
+	}
+	path, err := gamePath(request.URL.Query().Get("game"))
+	if err != nil {
+		writeError(writer, http.StatusNotFound, err)
+		return
+	}
+	if !reserveStream() {
+		writeError(writer, http.StatusServiceUnavailable, errTooManySessions)
+		return
+	}
+	defer releaseStream()
+
+	saveRoot, err := sessionSaveRoot(newSessionId())
+	if err != nil {
+		writeError(writer, http.StatusInternalServerError, err)
+		return
+	}
+	defer os.RemoveAll(saveRoot)
+	screen := &streamScreen{events: make(chan streamEvent, STREAM_BUFFER)}
+	game, err := loadSessionGame(path, screen, saveRoot)
+	if err != nil {
+		writeError(writer, http.StatusUnprocessableEntity, err)
+		return
+	}
+	websocket, err := acceptWebsocket(writer, request)
+	if err != nil {
+		writeError(writer, http.StatusBadRequest, err)
+		return
+	}
+	defer websocket.close()
+	// Closing the connection ends the input too, and with it the game
+	screen.overflow = func() { websocket.connection.Close() }
+
+	// Commands from the browser are read by the game as lines of input. When
+	// the browser goes away, the input runs out and the game ends
+	commands, commandWriter := io.Pipe()
+	defer commands.Close()
+	go func() {
+		for {
+			message, err := websocket.readMessage()
+			if err != nil {
+				break
+			}
+			if _, err := io.WriteString(commandWriter, strings.ReplaceAll(message, "\n", " ")+"\n"); err != nil {
+				break
+			}
+		}
+		commandWriter.Close()
+	}()
+
+	sent := make(chan struct{})
+	go func() {
+		for event := range screen.events {
+			data, _ := json.Marshal(event)
+			websocket.writeMessage(data)
+		}
+		close(sent)
+	}()
+
+	game.inputReader = bufio.NewReader(commands)
+	game.run(func() {
+		game.startGame()
+		game.playInteractively(nil, nil)
+	})
+	screen.send(streamEvent{Type: "over"})
+	close(screen.events)
+	<-sent
+}
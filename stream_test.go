@@ -0,0 +1,26 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+// A browser that stops reading gets disconnected once, and the game goes on
+// without waiting for it
+func TestStreamOverflow(t *testing.T) {
+	overflows := 0
+	screen := &streamScreen{
+		events:   make(chan streamEvent, 2),
+		overflow: func() { overflows++ },
+	}
+	for i := 0; i < 5; i++ {
+		fmt.Fprintf(screen, "line %d\n", i)
+	}
+	screen.clearWindow(WINDOW_MAIN)
+	if overflows != 1 {
+		t.Errorf("overflowed %d times, wanted once", overflows)
+	}
+	if len(screen.events) != 2 {
+		t.Errorf("got %d events queued, wanted the 2 that fit", len(screen.events))
+	}
+}
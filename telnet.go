@@ -0,0 +1,397 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"runtime/debug"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// The telnet command lets players connect with telnet, or anything else that
+// sends lines of text over TCP, and play like at a terminal. Each connection
+// plays a game of its own, and keeps its saved games until it's closed. What's
+// sent to a player is queued and sent by a goroutine of its own, so that a
+// player who stops reading only holds up themselves, and is disconnected when
+// TELNET_OUTPUT_BUFFER writes are waiting.
+
+const (
+	DEFAULT_TELNET_ADDRESS  string        = "localhost:2323"
+	DEFAULT_MAX_CONNECTIONS int           = 20
+	DEFAULT_IDLE_TIMEOUT    time.Duration = 15 * time.Minute
+	DEFAULT_TELNET_WIDTH    int           = 80
+	TELNET_WRITE_TIMEOUT    time.Duration = 10 * time.Second
+	TELNET_OUTPUT_BUFFER    int           = 256
+	TELNET_SHUTDOWN_TIMEOUT time.Duration = 10 * time.Second
+
+	// Telnet commands, which are taken out of the input
+	TELNET_SE   byte = 240
+	TELNET_SB   byte = 250
+	TELNET_WILL byte = 251
+	TELNET_DONT byte = 254
+	TELNET_IAC  byte = 255
+)
+
+var (
+	telnetGame        string
+	telnetWidth       int
+	idleTimeout       time.Duration
+	maxConnections    int
+	telnetConnections = make(map[net.Conn]*telnetOutput)
+	telnetMutex       sync.Mutex
+	telnetPlayers     sync.WaitGroup
+)
+
+// Where the input is in a telnet command
+const (
+	telnetData = iota
+	telnetCommand
+	telnetOption
+	telnetSubnegotiation
+	telnetSubnegotiationCommand
+)
+
+// Input from a connection, without telnet commands. Players who don't type
+// anything for too long are disconnected
+type telnetInput struct {
+	output *telnetOutput
+	state  int
+}
+
+func (input *telnetInput) Read(text []byte) (int, error) {
+	raw := make([]byte, len(text))
+	for {
+		connection := input.output.connection
+		if idleTimeout > 0 {
+			connection.SetReadDeadline(time.Now().Add(idleTimeout))
+		}
+		length, err := connection.Read(raw)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				fmt.Fprintf(input.output, localize("\nDisconnected after %v without input\n"), idleTimeout)
+			}
+			// Whatever went wrong, the game ends as if the input had run out
+			return 0, io.EOF
+		}
+		if filtered := input.filter(raw[:length], text[:0]); len(filtered) > 0 {
+			return len(filtered), nil
+		}
+	}
+}
+
+func (input *telnetInput) filter(raw []byte, filtered []byte) []byte {
+	for _, character := range raw {
+		switch input.state {
+		case telnetData:
+			if character == TELNET_IAC {
+				input.state = telnetCommand
+			} else if character != 0 {
+				filtered = append(filtered, character)
+			}
+		case telnetCommand:
+			input.state = telnetData
+			if character == TELNET_IAC {
+				filtered = append(filtered, character)
+			} else if character >= TELNET_WILL && character <= TELNET_DONT {
+				input.state = telnetOption
+			} else if character == TELNET_SB {
+				input.state = telnetSubnegotiation
+			}
+		case telnetOption:
+			input.state = telnetData
+		case telnetSubnegotiation:
+			if character == TELNET_IAC {
+				input.state = telnetSubnegotiationCommand
+			}
+		case telnetSubnegotiationCommand:
+			input.state = telnetSubnegotiation
+			if character == TELNET_SE {
+				input.state = telnetData
+			}
+		}
+	}
+	return filtered
+}
+
+// Output to a connection, with lines ended the way telnet wants them. Writes
+// are queued without waiting, from any goroutine, and sent in the order they
+// were made. When the connection is done with, it's closed once what's queued
+// has been sent. A player who lets the queue fill up is disconnected at once
+type telnetOutput struct {
+	connection net.Conn
+	queue      chan []byte
+	sent       chan struct{}
+	mutex      sync.Mutex
+	closed     bool
+}
+
+var errTelnetOverflow = errors.New("player has stopped reading")
+
+func newTelnetOutput(connection net.Conn) *telnetOutput {
+	output := &telnetOutput{
+		connection: connection,
+		queue:      make(chan []byte, TELNET_OUTPUT_BUFFER),
+		sent:       make(chan struct{}),
+	}
+	go output.send()
+	return output
+}
+
+func (output *telnetOutput) send() {
+	defer close(output.sent)
+	defer output.connection.Close()
+	for text := range output.queue {
+		output.connection.SetWriteDeadline(time.Now().Add(TELNET_WRITE_TIMEOUT))
+		if _, err := output.connection.Write(text); err != nil {
+			// The rest is thrown away, and the game ends as its input runs out
+			output.disconnect()
+			output.connection.Close()
+		}
+	}
+}
+
+func (output *telnetOutput) Write(text []byte) (int, error) {
+	output.mutex.Lock()
+	defer output.mutex.Unlock()
+	if output.closed {
+		return 0, net.ErrClosed
+	}
+	select {
+	case output.queue <- bytes.ReplaceAll(text, []byte("\n"), []byte("\r\n")):
+		return len(text), nil
+	default:
+		// What's queued can't be sent to a player who doesn't read it
+		output.closed = true
+		close(output.queue)
+		output.connection.Close()
+		return 0, errTelnetOverflow
+	}
+}
+
+// Send what's queued and close the connection, without waiting for it
+func (output *telnetOutput) disconnect() {
+	output.mutex.Lock()
+	defer output.mutex.Unlock()
+	if !output.closed {
+		output.closed = true
+		close(output.queue)
+	}
+}
+
+// Send what's queued and close the connection
+func (output *telnetOutput) close() {
+	output.disconnect()
+	<-output.sent
+}
+
+// The screen of a player, wrapped at the width given with --width
+type telnetScreen struct {
+	formatter *outputFormatter
+}
+
+func newTelnetScreen(output *telnetOutput) *telnetScreen {
+	formatter := newOutputFormatter(output)
+	formatter.wrapAt = func() int { return telnetWidth }
+	return &telnetScreen{formatter: formatter}
+}
+
+func (screen *telnetScreen) Write(text []byte) (int, error) {
+	return screen.formatter.Write(text)
+}
+
+func (screen *telnetScreen) setStyle(style glkStyle) {
+	if !noANSI {
+		fmt.Fprint(screen.formatter, styleCode(style))
+	}
+}
+
+func (screen *telnetScreen) hasWindow(window glkWindow) bool {
+	return window == WINDOW_MAIN
+}
+
+func (screen *telnetScreen) drawWindow(window glkWindow, text string) {}
+
+func (screen *telnetScreen) clearWindow(window glkWindow) {
+	if window != WINDOW_MAIN {
+		return
+	}
+	if noANSI {
+		fmt.Fprintln(screen.formatter)
+		return
+	}
+	fmt.Fprint(screen.formatter, "\033[H\033[2J")
+}
+
+func (screen *telnetScreen) delay(duration time.Duration) {
+	time.Sleep(duration)
+}
+
+func (screen *telnetScreen) readLine(input *bufio.Reader) (string, error) {
+	return input.ReadString('\n')
+}
+
+func serveTelnet(arguments []string) {
+	options := flag.NewFlagSet("telnet", flag.ExitOnError)
+	options.StringVar(&listenAddress, "listen", DEFAULT_TELNET_ADDRESS, "Address to listen on")
+	options.StringVar(&libraryDirectory, "library", "", "Directory with the games players can choose from")
+	options.StringVar(&telnetGame, "game", "", "Game to play, instead of letting players choose")
+	options.BoolVar(&sharedWorldMode, "shared", false, "Let everyone play the game given with --game together, in one world")
+	options.IntVar(&maxConnections, "max-connections", DEFAULT_MAX_CONNECTIONS, "Number of players that can be connected at once")
+	options.DurationVar(&idleTimeout, "idle-timeout", DEFAULT_IDLE_TIMEOUT, "Disconnect players who don't type anything for this long (0 never does)")
+	options.IntVar(&telnetWidth, "width", DEFAULT_TELNET_WIDTH, "Wrap output at this many columns (0 doesn't wrap)")
+	options.BoolVar(&noANSI, "no-ansi", false, "Don't send terminal control codes")
+	options.StringVar(&selectedLanguage, "lang", "", "Language of the interpreter messages: en or de")
+	options.BoolVar(&fuzzyMatching, "fuzzy", false, "Understand words with small typing mistakes")
+	options.Parse(arguments)
+
+	setNoiseWords(DEFAULT_NOISE_WORDS)
+	if err := selectLanguage(); err != nil {
+		log.Fatal(err)
+	}
+	if sharedWorldMode {
+		if telnetGame == "" {
+			log.Fatal("--shared needs a game given with --game")
+		}
+		var err error
+		if telnetWorld, err = newSharedWorld(telnetGame); err != nil {
+			log.Fatal(err)
+		}
+	}
+	listener, err := net.Listen("tcp", listenAddress)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Accepting players on %s", listener.Addr())
+
+	// Stop taking players on a signal, and let the ones playing finish
+	shuttingDown := make(chan struct{})
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		<-signals
+		close(shuttingDown)
+		listener.Close()
+	}()
+
+	for {
+		connection, err := listener.Accept()
+		if err != nil {
+			select {
+			case <-shuttingDown:
+			default:
+				log.Print(err)
+				continue
+			}
+			break
+		}
+		output := newTelnetOutput(connection)
+		if !addTelnetConnection(connection, output) {
+			fmt.Fprintln(output, localize("Too many players at the moment. Try again later"))
+			output.disconnect()
+			continue
+		}
+		go playOverTelnet(connection, output)
+	}
+
+	log.Print("Shutting down")
+	disconnectPlayers(localize("\nThe server is shutting down. Goodbye!\n"))
+	finished := make(chan struct{})
+	go func() {
+		telnetPlayers.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-time.After(TELNET_SHUTDOWN_TIMEOUT):
+		log.Print("Gave up waiting for games to end")
+	}
+}
+
+func addTelnetConnection(connection net.Conn, output *telnetOutput) bool {
+	telnetMutex.Lock()
+	defer telnetMutex.Unlock()
+	if len(telnetConnections) >= maxConnections {
+		return false
+	}
+	telnetConnections[connection] = output
+	telnetPlayers.Add(1)
+	return true
+}
+
+func removeTelnetConnection(connection net.Conn) {
+	telnetMutex.Lock()
+	delete(telnetConnections, connection)
+	telnetMutex.Unlock()
+	telnetPlayers.Done()
+}
+
+// Tell everyone connected, and close their connections. Their games end as
+// if their input ran out
+func disconnectPlayers(message string) {
+	telnetMutex.Lock()
+	defer telnetMutex.Unlock()
+	for _, output := range telnetConnections {
+		fmt.Fprint(output, message)
+		output.disconnect()
+	}
+}
+
+// Let a player choose a game, unless --game gives one, and play it until
+// they quit or go away
+func playOverTelnet(connection net.Conn, output *telnetOutput) {
+	defer removeTelnetConnection(connection)
+	defer output.close()
+	log.Printf("%s connected", connection.RemoteAddr())
+	defer log.Printf("%s disconnected", connection.RemoteAddr())
+
+	// A game that goes wrong only ends the connection it's played on. A
+	// shared world has been left and unlocked by then
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			log.Printf("%s: game stopped by an error: %v\n%s", connection.RemoteAddr(), recovered, debug.Stack())
+		}
+	}()
+
+	saveRoot, err := sessionSaveRoot(newSessionId())
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	defer os.RemoveAll(saveRoot)
+	if telnetWorld != nil {
+		playInSharedWorld(telnetWorld, newTelnetScreen(output), &telnetInput{output: output}, saveRoot)
+		return
+	}
+
+	game := newSessionEngine(newTelnetScreen(output), &telnetInput{output: output}, saveRoot)
+	game.run(func() {
+		game.gameFile = telnetGame
+		if game.gameFile == "" {
+			var err error
+			if game.gameFile, err = game.chooseFromLibrary(); err != nil {
+				fmt.Fprintln(game.output, err)
+				return
+			} else if game.gameFile == "" {
+				return
+			}
+		}
+		if err := game.loadGameDataFile(game.gameFile); err != nil {
+			fmt.Fprintf(game.output, localize("Couldn't load \"%s\": %v\n"), game.gameFile, err)
+			return
+		}
+		game.initializeGameState()
+		game.showIntro()
+		game.startGame()
+		game.playInteractively(nil, nil)
+	})
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"testing"
+)
+
+// What's written is sent with telnet line endings once the output is closed
+func TestTelnetOutput(t *testing.T) {
+	server, client := net.Pipe()
+	output := newTelnetOutput(server)
+	fmt.Fprint(output, "OK\nTell me what to do\n")
+	go output.close()
+
+	received, err := io.ReadAll(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(received) != "OK\r\nTell me what to do\r\n" {
+		t.Errorf("got %q", received)
+	}
+}
+
+// A player who doesn't read doesn't hold up the writes, and is disconnected
+// once the queue is full
+func TestTelnetOutputOverflow(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+	output := newTelnetOutput(server)
+
+	var err error
+	for i := 0; i <= TELNET_OUTPUT_BUFFER+1 && err == nil; i++ {
+		_, err = fmt.Fprintf(output, "line %d\n", i)
+	}
+	if err != errTelnetOverflow {
+		t.Fatalf("got error %v after filling the queue, wanted %v", err, errTelnetOverflow)
+	}
+	if _, err := fmt.Fprintln(output, "more"); err == nil {
+		t.Error("could still write after the queue overflowed")
+	}
+	output.close()
+	if _, err := client.Read(make([]byte, 1)); err == nil {
+		t.Error("the connection is still open after the queue overflowed")
+	}
+}
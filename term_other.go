@@ -0,0 +1,21 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd)
+
+package main
+
+import "os"
+
+// The terminal size can't be asked for on this system
+func terminalSize(file *os.File) (int, int, bool) {
+	return 0, 0, false
+}
+
+// Without a way to tell, output is taken to be a terminal
+func isTerminal(file *os.File) bool {
+	return true
+}
+
+// Raw terminal input isn't available on this system, so input is read a line
+// at a time
+func enableRawMode(file *os.File) (func(), bool) {
+	return nil, false
+}
@@ -0,0 +1,50 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// Ask the terminal for its size in columns and rows
+func terminalSize(file *os.File) (int, int, bool) {
+	var size struct {
+		rows, columns, xPixels, yPixels uint16
+	}
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, file.Fd(), uintptr(syscall.TIOCGWINSZ), uintptr(unsafe.Pointer(&size)))
+	if errno != 0 || size.columns == 0 || size.rows == 0 {
+		return 0, 0, false
+	}
+	return int(size.columns), int(size.rows), true
+}
+
+// Only a terminal has terminal settings, so this tells terminals from pipes
+// and files
+func isTerminal(file *os.File) bool {
+	var settings syscall.Termios
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, file.Fd(), ioctlReadTermios, uintptr(unsafe.Pointer(&settings)))
+	return errno == 0
+}
+
+// Let the program see every key as it is pressed, without the terminal
+// echoing it or waiting for a whole line. The returned function puts the
+// terminal back the way it was
+func enableRawMode(file *os.File) (func(), bool) {
+	var original syscall.Termios
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, file.Fd(), ioctlReadTermios, uintptr(unsafe.Pointer(&original))); errno != 0 {
+		return nil, false
+	}
+	raw := original
+	raw.Lflag &^= syscall.ICANON | syscall.ECHO | syscall.ISIG | syscall.IEXTEN
+	raw.Iflag &^= syscall.ICRNL | syscall.IXON
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, file.Fd(), ioctlWriteTermios, uintptr(unsafe.Pointer(&raw))); errno != 0 {
+		return nil, false
+	}
+	return func() {
+		syscall.Syscall(syscall.SYS_IOCTL, file.Fd(), ioctlWriteTermios, uintptr(unsafe.Pointer(&original)))
+	}, true
+}
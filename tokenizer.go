@@ -0,0 +1,46 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Commands are split into words at any run of spaces, tabs or punctuation, so
+// that GET  LAMP, GET LAMP. and GET,LAMP all mean the same. Noise words like
+// THE in GET THE LAMP are left out, since the games only look at the first
+// two words.
+
+const DEFAULT_NOISE_WORDS string = "THE,A,AN,TO,AT"
+
+var (
+	noiseWordList string
+	noiseWords    map[string]bool
+)
+
+// Set up the noise words from a comma separated list
+func setNoiseWords(list string) {
+	noiseWords = make(map[string]bool)
+	for _, word := range strings.Split(list, ",") {
+		if word = strings.TrimSpace(word); word != "" {
+			noiseWords[foldCase(word)] = true
+		}
+	}
+}
+
+// Split a command into words, without punctuation and noise words. If the
+// command is only noise words, they are kept, as the game might know them
+func tokenizeInput(input string) []string {
+	words := strings.FieldsFunc(input, func(character rune) bool {
+		return unicode.IsSpace(character) || unicode.IsPunct(character) || unicode.IsSymbol(character)
+	})
+	var meaningful []string
+	for _, word := range words {
+		if !noiseWords[foldCase(word)] {
+			meaningful = append(meaningful, word)
+		}
+	}
+	if len(meaningful) == 0 {
+		return words
+	}
+	return meaningful
+}
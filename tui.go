@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// The split-window mode works like the original interpreters: the room
+// description stays in a window at the top of the screen, with a status line
+// under it, and the messages scroll in the rest of the screen. The message
+// window is a terminal scrolling region, so no cursor addressing is needed
+// while messages are printed.
+
+const (
+	DEFAULT_TERMINAL_HEIGHT int = 24
+	DEFAULT_TERMINAL_WIDTH  int = 80
+	TUI_MIN_MESSAGE_LINES   int = 5
+	TUI_ROOM_PANE_LINES     int = 10
+	TUI_SCROLLBACK_LINES    int = 1000
+)
+
+var (
+	tuiActive      bool
+	tuiHeight      int
+	tuiMode        bool
+	tuiPaneLines   int
+	tuiScrollback  []string
+	tuiPartialLine string
+	tuiWidth       int
+)
+
+// Sends messages to the terminal, and keeps them to be able to draw the
+// message window again when the terminal changes size
+type tuiMessageWriter struct{}
+
+func (writer tuiMessageWriter) Write(text []byte) (int, error) {
+	lines := strings.Split(tuiPartialLine+string(text), "\n")
+	tuiScrollback = append(tuiScrollback, lines[:len(lines)-1]...)
+	if len(tuiScrollback) > TUI_SCROLLBACK_LINES {
+		tuiScrollback = tuiScrollback[len(tuiScrollback)-TUI_SCROLLBACK_LINES:]
+	}
+	tuiPartialLine = lines[len(lines)-1]
+	return os.Stdout.Write(text)
+}
+
+// Size of the terminal, from the terminal itself, the environment, or the
+// size of the original screens as a last resort
+func screenSize() (int, int) {
+	if width, height, known := terminalSize(os.Stdout); known {
+		return width, height
+	}
+	width, err := strconv.Atoi(os.Getenv("COLUMNS"))
+	if err != nil || width < 1 {
+		width = DEFAULT_TERMINAL_WIDTH
+	}
+	height, err := strconv.Atoi(os.Getenv("LINES"))
+	if err != nil || height < 1 {
+		height = DEFAULT_TERMINAL_HEIGHT
+	}
+	return width, height
+}
+
+// The messages go to the scrolling region, and the room window and the status
+// line are drawn above it
+type tuiDisplay struct {
+	terminalDisplay
+	game *gameEngine
+}
+
+// Styles are sent to the terminal only, to keep them out of the scrollback
+func (display tuiDisplay) setStyle(style glkStyle) {
+	os.Stdout.WriteString(styleCode(style))
+}
+
+func (display tuiDisplay) hasWindow(window glkWindow) bool {
+	return true
+}
+
+// Draw a window, leaving the cursor where it was in the message window. When
+// the terminal has changed size, everything is laid out again instead
+func (display tuiDisplay) drawWindow(window glkWindow, text string) {
+	if width, height := screenSize(); width != tuiWidth || height != tuiHeight {
+		display.game.layoutTUI()
+		return
+	}
+
+	var screen strings.Builder
+	screen.WriteString("\0337")
+	switch window {
+	case WINDOW_ROOM:
+		lines := wrapLines(strings.TrimRight(text, "\n"), tuiWidth)
+		if len(lines) > tuiPaneLines {
+			lines = lines[:tuiPaneLines]
+			lines[tuiPaneLines-1] = "..."
+		}
+		for row := 0; row < tuiPaneLines; row++ {
+			fmt.Fprintf(&screen, "\033[%d;1H\033[2K", row+1)
+			if row < len(lines) {
+				screen.WriteString(lines[row])
+			}
+		}
+	case WINDOW_STATUS:
+		status := []rune(formatText(text))
+		if len(status) > tuiWidth {
+			status = status[:tuiWidth]
+		}
+		fmt.Fprintf(&screen, "\033[%d;1H\033[7m%-*s\033[0m", tuiPaneLines+1, tuiWidth, string(status))
+	case WINDOW_MAIN:
+		return
+	}
+	screen.WriteString("\0338")
+	os.Stdout.WriteString(screen.String())
+}
+
+func (display tuiDisplay) clearWindow(window glkWindow) {
+	if window == WINDOW_MAIN {
+		display.game.clearMessagePane()
+		return
+	}
+	display.drawWindow(window, "")
+}
+
+func (game *gameEngine) startTUI() {
+	tuiActive = true
+	game.output = tuiDisplay{terminalDisplay{newOutputFormatter(tuiMessageWriter{})}, game}
+	game.layoutTUI()
+}
+
+// Divide the screen into the windows, and draw all of them
+func (game *gameEngine) layoutTUI() {
+	tuiWidth, tuiHeight = screenSize()
+	tuiPaneLines = TUI_ROOM_PANE_LINES
+	if tuiHeight-tuiPaneLines-1 < TUI_MIN_MESSAGE_LINES {
+		tuiPaneLines = (tuiHeight - 1) / 2
+	}
+
+	firstMessageLine := tuiPaneLines + 2
+	fmt.Fprintf(os.Stdout, "\033[H\033[2J\033[%d;%dr\033[%d;1H", firstMessageLine, tuiHeight, firstMessageLine)
+
+	// Fill the message window with whatever fits of the scrollback
+	messageLines := tuiHeight - firstMessageLine + 1
+	var wrapped []string
+	for _, line := range tuiScrollback {
+		wrapped = append(wrapped, wrapLines(line, tuiWidth)...)
+	}
+	if len(wrapped) > messageLines-1 {
+		wrapped = wrapped[len(wrapped)-messageLines+1:]
+	}
+	for _, line := range wrapped {
+		fmt.Fprintln(os.Stdout, line)
+	}
+	fmt.Fprint(os.Stdout, tuiPartialLine)
+	game.updateWindows()
+}
+
+func (game *gameEngine) statusLineText() string {
+	score := 0
+	if game.numberOfTreasures > 0 {
+		score = game.countStoredTreasures() * PERCENT_UNITS / game.numberOfTreasures
+	}
+	return fmt.Sprintf(localize(" Turns: %d   Light: %d   Score: %d%%"),
+		game.turnCounter, game.alternateCounter[COUNTER_TIME_LIMIT], score)
+}
+
+// What the player types is echoed by the terminal, but it needs to go in the
+// scrollback as well
+func recordTUIInput(input string) {
+	tuiScrollback = append(tuiScrollback, tuiPartialLine+input)
+	tuiPartialLine = ""
+}
+
+func (game *gameEngine) clearMessagePane() {
+	tuiScrollback = nil
+	tuiPartialLine = ""
+	fmt.Fprintf(os.Stdout, "\033[%d;1H\033[J", tuiPaneLines+2)
+	game.updateWindows()
+}
+
+// Give the whole screen back to the terminal
+func (game *gameEngine) closeTUI() {
+	if !tuiActive {
+		return
+	}
+	tuiActive = false
+	game.output = terminalDisplay{newOutputFormatter(os.Stdout)}
+	fmt.Fprintf(os.Stdout, "\033[r\033[%d;1H\n", tuiHeight)
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Just enough of the WebSocket protocol (RFC 6455) for a browser to play
+// games: text messages both ways, pings and closing. Messages from the browser
+// are small, so they are read whole.
+
+const (
+	WEBSOCKET_GUID             string        = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	WEBSOCKET_MAX_MESSAGE_SIZE int           = 4096
+	WEBSOCKET_WRITE_TIMEOUT    time.Duration = 10 * time.Second
+
+	OPCODE_CONTINUATION byte = 0x0
+	OPCODE_TEXT         byte = 0x1
+	OPCODE_BINARY       byte = 0x2
+	OPCODE_CLOSE        byte = 0x8
+	OPCODE_PING         byte = 0x9
+	OPCODE_PONG         byte = 0xa
+
+	FRAME_FINAL  byte = 0x80
+	FRAME_MASKED byte = 0x80
+)
+
+var errWebsocketClosed = errors.New("websocket closed")
+
+type websocketConnection struct {
+	connection net.Conn
+	reader     *bufio.Reader
+	writeMutex sync.Mutex
+}
+
+// Answer a request to open a WebSocket, taking over its connection
+func acceptWebsocket(writer http.ResponseWriter, request *http.Request) (*websocketConnection, error) {
+	key := request.Header.Get("Sec-WebSocket-Key")
+	if !headerContains(request.Header, "Connection", "upgrade") ||
+		!headerContains(request.Header, "Upgrade", "websocket") ||
+		request.Header.Get("Sec-WebSocket-Version") != "13" || key == "" {
+		return nil, errors.New("not a websocket request")
+	}
+	hijacker, ok := writer.(http.Hijacker)
+	if !ok {
+		return nil, errors.New("connection can't be taken over")
+	}
+	connection, buffer, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha1.Sum([]byte(key + WEBSOCKET_GUID))
+	buffer.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(hash[:]) + "\r\n\r\n")
+	if err := buffer.Flush(); err != nil {
+		connection.Close()
+		return nil, err
+	}
+	return &websocketConnection{connection: connection, reader: buffer.Reader}, nil
+}
+
+func headerContains(header http.Header, name string, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, field := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(field), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Read the next text message, answering pings on the way
+func (websocket *websocketConnection) readMessage() (string, error) {
+	var message []byte
+	for {
+		final, opcode, payload, err := websocket.readFrame()
+		if err != nil {
+			return "", err
+		}
+		switch opcode {
+		case OPCODE_PING:
+			websocket.writeFrame(OPCODE_PONG, payload)
+			continue
+		case OPCODE_PONG:
+			continue
+		case OPCODE_CLOSE:
+			websocket.writeFrame(OPCODE_CLOSE, nil)
+			return "", errWebsocketClosed
+		}
+
+		message = append(message, payload...)
+		if len(message) > WEBSOCKET_MAX_MESSAGE_SIZE {
+			websocket.writeFrame(OPCODE_CLOSE, []byte{0x03, 0xf1}) // 1009, message too big
+			return "", errors.New("websocket message too big")
+		}
+		if final {
+			return string(message), nil
+		}
+	}
+}
+
+func (websocket *websocketConnection) readFrame() (bool, byte, []byte, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(websocket.reader, header); err != nil {
+		return false, 0, nil, err
+	}
+	final := header[0]&FRAME_FINAL != 0
+	opcode := header[0] & 0x0f
+	if header[1]&FRAME_MASKED == 0 {
+		return false, 0, nil, errors.New("unmasked websocket frame from client")
+	}
+
+	length := uint64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		extended := make([]byte, 2)
+		if _, err := io.ReadFull(websocket.reader, extended); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(extended))
+	case 127:
+		extended := make([]byte, 8)
+		if _, err := io.ReadFull(websocket.reader, extended); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(extended)
+	}
+	if length > uint64(WEBSOCKET_MAX_MESSAGE_SIZE) {
+		websocket.writeFrame(OPCODE_CLOSE, []byte{0x03, 0xf1})
+		return false, 0, nil, errors.New("websocket frame too big")
+	}
+
+	mask := make([]byte, 4)
+	if _, err := io.ReadFull(websocket.reader, mask); err != nil {
+		return false, 0, nil, err
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(websocket.reader, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return final, opcode, payload, nil
+}
+
+// Send a text message
+func (websocket *websocketConnection) writeMessage(message []byte) error {
+	return websocket.writeFrame(OPCODE_TEXT, message)
+}
+
+// Frames from the server aren't masked
+func (websocket *websocketConnection) writeFrame(opcode byte, payload []byte) error {
+	websocket.writeMutex.Lock()
+	defer websocket.writeMutex.Unlock()
+
+	header := []byte{FRAME_FINAL | opcode}
+	length := len(payload)
+	switch {
+	case length < 126:
+		header = append(header, byte(length))
+	case length <= 0xffff:
+		header = append(header, 126, 0, 0)
+		binary.BigEndian.PutUint16(header[2:], uint16(length))
+	default:
+		header = append(header, 127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(header[2:], uint64(length))
+	}
+	// A browser that stops reading mustn't hold up the game
+	websocket.connection.SetWriteDeadline(time.Now().Add(WEBSOCKET_WRITE_TIMEOUT))
+	if _, err := websocket.connection.Write(append(header, payload...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (websocket *websocketConnection) close() {
+	websocket.writeFrame(OPCODE_CLOSE, nil)
+	websocket.connection.Close()
+}